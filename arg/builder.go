@@ -20,6 +20,11 @@ func In(values ...interface{}) *InExpr {
 	}
 }
 
+// HasPrefix 字符串参数的前缀匹配
+func HasPrefix(prefix string) *HasPrefixExpr {
+	return &HasPrefixExpr{prefix: prefix}
+}
+
 // Field 属性值匹配表达式
 func Field(name string) *Builder {
 	return (&Builder{}).Field(name)
@@ -3,6 +3,7 @@ package arg
 import (
 	"fmt"
 	"reflect"
+	"strings"
 )
 
 // Expr 表达式接口, 实现了 equals、any、in、field(x)等表达式匹配
@@ -126,3 +127,38 @@ outer:
 	}
 	return false, nil
 }
+
+// HasPrefixExpr 字符串参数的前缀匹配表达式
+type HasPrefixExpr struct {
+	prefix string
+}
+
+// Resolve HasPrefixExpr 表达式解析, 参数类型必须是字符串
+func (h *HasPrefixExpr) Resolve(types []reflect.Type, isVariadic bool) error {
+	if len(types) != 1 {
+		return fmt.Errorf("HasPrefixExpr.Resolve status error")
+	}
+	typ := types[0]
+	if isVariadic && typ.Kind() == reflect.Slice {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.String {
+		return fmt.Errorf("HasPrefixExpr requires string arg, actual: %s", typ)
+	}
+	return nil
+}
+
+// Eval 执行 HasPrefixExpr 表达式
+func (h *HasPrefixExpr) Eval(input []reflect.Value, isVariadic bool) (bool, error) {
+	if len(input) != 1 {
+		return false, fmt.Errorf("HasPrefixExpr.Eval status error")
+	}
+	v := input[0]
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.String {
+		return false, nil
+	}
+	return strings.HasPrefix(v.String(), h.prefix), nil
+}
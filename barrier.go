@@ -0,0 +1,149 @@
+// Package mocker 定义了 mock 的外层用户使用 API 定义,
+// 包括函数、方法、接口、未导出函数(或方法的)的 Mocker 的实现。
+// 当前文件实现了调用屏障: 被 mock 的函数或方法的每一次调用都停在 mock 回调中,
+// 直到测试调用 Release 放行, 用于精确地控制多个协程之间的执行顺序, 复现并发问题。
+package mocker
+
+import (
+	"reflect"
+	"sync"
+	"time"
+)
+
+// barrierNotifySize Calls 通知通道的缓冲大小, 缓冲满时丢弃新的通知
+const barrierNotifySize = 1024
+
+// Barrier 调用屏障, 由 DefMocker.Block 或 MethodMocker.Block 创建
+type Barrier struct {
+	name string
+	// receiver 第一个参数是否为接收体, 通知中不包含接收体
+	receiver bool
+
+	lock sync.Mutex
+	// changed 状态变化时关闭并重新创建, 用于唤醒等待的协程
+	changed chan struct{}
+	// permits 可以放行的调用数量
+	permits int
+	// all 是否放行所有调用
+	all bool
+	// arrived 到达屏障的调用数量
+	arrived int
+	// parked 停在屏障中的调用数量
+	parked int
+	notify chan Invocation
+}
+
+// newBarrier 创建调用屏障
+func newBarrier(name string, receiver bool) *Barrier {
+	return &Barrier{
+		name:     name,
+		receiver: receiver,
+		changed:  make(chan struct{}),
+		notify:   make(chan Invocation, barrierNotifySize),
+	}
+}
+
+// Release 放行 n 个调用, 包括已经停在屏障中的调用和之后到达的调用
+func (b *Barrier) Release(n int) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.permits += n
+	b.signal()
+}
+
+// ReleaseAll 放行所有停在屏障中的调用, 之后到达的调用也不再停留
+func (b *Barrier) ReleaseAll() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.all = true
+	b.signal()
+}
+
+// WaitCalls 等待累计到达屏障的调用数量达到 n, 超时返回 false
+func (b *Barrier) WaitCalls(n int, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		b.lock.Lock()
+		arrived, changed := b.arrived, b.changed
+		b.lock.Unlock()
+		if arrived >= n {
+			return true
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			return false
+		}
+	}
+}
+
+// Parked 停在屏障中等待放行的调用数量
+func (b *Barrier) Parked() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.parked
+}
+
+// Calls 调用到达屏障时的通知, 通知中的 Results 为空; 缓冲满时丢弃新的通知
+func (b *Barrier) Calls() <-chan Invocation {
+	return b.notify
+}
+
+// intercept 拦截 mock 回调, 调用回调之前先在屏障中等待放行
+func (b *Barrier) intercept(imp interface{}) interface{} {
+	impV := reflect.ValueOf(imp)
+	return reflect.MakeFunc(impV.Type(), func(params []reflect.Value) []reflect.Value {
+		b.pass(params)
+		if impV.Type().IsVariadic() {
+			return impV.CallSlice(params)
+		}
+		return impV.Call(params)
+	}).Interface()
+}
+
+// pass 记录到达的调用并等待放行
+func (b *Barrier) pass(params []reflect.Value) {
+	args := params
+	if b.receiver && len(args) > 0 {
+		args = args[1:]
+	}
+	select {
+	case b.notify <- Invocation{Name: b.name, Args: values2I(args)}:
+	default:
+	}
+
+	b.lock.Lock()
+	b.arrived++
+	b.parked++
+	b.signal()
+	for !b.all && b.permits == 0 {
+		changed := b.changed
+		b.lock.Unlock()
+		<-changed
+		b.lock.Lock()
+	}
+	if !b.all {
+		b.permits--
+	}
+	b.parked--
+	b.signal()
+	b.lock.Unlock()
+}
+
+// signal 唤醒等待状态变化的协程, 调用时需要持有锁
+func (b *Barrier) signal() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// zeroImp 返回零值的 mock 回调
+func zeroImp(funcTyp reflect.Type) interface{} {
+	return reflect.MakeFunc(funcTyp, func([]reflect.Value) []reflect.Value {
+		results := make([]reflect.Value, funcTyp.NumOut())
+		for i := range results {
+			results[i] = reflect.Zero(funcTyp.Out(i))
+		}
+		return results
+	}).Interface()
+}
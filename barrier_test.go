@@ -0,0 +1,112 @@
+package mocker_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/tencent/goom"
+)
+
+// TestUnitBarrierTestSuite 测试入口
+func TestUnitBarrierTestSuite(t *testing.T) {
+	suite.Run(t, new(barrierTestSuite))
+}
+
+type barrierTestSuite struct {
+	suite.Suite
+}
+
+// TestUnitBarrierRelease 测试调用停在屏障中, 按照 Release 的数量放行
+func (s *barrierTestSuite) TestUnitBarrierRelease() {
+	mock := mocker.Create()
+	defer mock.Reset()
+	barrier := mock.Func(saveRecord).Block()
+
+	results := make(chan error, 2)
+	for i := 1; i <= 2; i++ {
+		go func(id int) {
+			results <- saveRecord(id)
+		}(i)
+	}
+	s.True(barrier.WaitCalls(2, time.Second), "wait calls check")
+	s.Equal(2, barrier.Parked(), "parked check")
+	s.Empty(results, "blocked check")
+
+	ids := []interface{}{(<-barrier.Calls()).Args[0], (<-barrier.Calls()).Args[0]}
+	s.ElementsMatch([]interface{}{1, 2}, ids, "calls check")
+
+	barrier.Release(1)
+	s.NoError(<-results, "zero value check")
+	s.Equal(1, barrier.Parked(), "parked check")
+	barrier.ReleaseAll()
+	s.NoError(<-results, "zero value check")
+	s.NoError(saveRecord(3), "release all check")
+	s.False(barrier.WaitCalls(4, 10*time.Millisecond), "timeout check")
+}
+
+// TestUnitBarrierReturn 测试 Block 之后指定返回值
+func (s *barrierTestSuite) TestUnitBarrierReturn() {
+	mock := mocker.Create()
+	defer mock.Reset()
+	errDup := errors.New("duplicate")
+	barrier := mock.Func(saveRecord).Block()
+	mock.Func(saveRecord).Return(nil).When(1).Return(errDup)
+
+	barrier.Release(2)
+	s.Equal(errDup, saveRecord(1), "when check")
+	s.NoError(saveRecord(2), "default check")
+}
+
+// TestUnitBarrierMethod 测试方法的调用屏障和已有的回调
+func (s *barrierTestSuite) TestUnitBarrierMethod() {
+	mock := mocker.Create()
+	defer mock.Reset()
+	method := mock.Struct(&recordStore{}).Method("Save")
+	method.Apply(func(_ *recordStore, id int) error {
+		return errors.New("mocked")
+	})
+	barrier := method.(*mocker.MethodMocker).Block()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- (&recordStore{}).Save(1)
+	}()
+	call := <-barrier.Calls()
+	s.Equal([]interface{}{1}, call.Args, "receiver excluded check")
+	barrier.Release(1)
+	s.EqualError(<-done, "mocked", "apply check")
+}
+
+// TestUnitBarrierCancel 测试取消 mock 时放行停在屏障中的调用
+func (s *barrierTestSuite) TestUnitBarrierCancel() {
+	mock := mocker.Create()
+	barrier := mock.Func(saveRecord).Block()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = saveRecord(1)
+	}()
+	s.True(barrier.WaitCalls(1, time.Second), "wait calls check")
+	mock.Reset()
+	wg.Wait()
+	s.EqualError(saveRecord(1), "saved 1", "reset check")
+}
+
+// saveRecord 保存记录
+func saveRecord(id int) error {
+	return fmt.Errorf("saved %d", id)
+}
+
+// recordStore 记录存储
+type recordStore struct{}
+
+// Save 保存记录
+func (r *recordStore) Save(id int) error {
+	return saveRecord(id)
+}
@@ -75,18 +75,77 @@ func (b *Builder) Interface(iFace interface{}) *CachedInterfaceMocker {
 	return cachedMocker
 }
 
+// AllImplementations 指定接口类型, 对该接口在二进制中的所有实现类的方法同时进行 mock
+// iFace 接口类型的指针, 比如: mock.AllImplementations((*Store)(nil)).Method("Get").Return("v")
+func (b *Builder) AllImplementations(iFace interface{}) *ImplementationsMocker {
+	mKey := fmt.Sprintf("impls_%s", reflect.TypeOf(iFace).String())
+	if mocker, ok := b.mockers[mKey]; ok && !mocker.Canceled() {
+		b.reset2CurPkg()
+		return mocker.(*ImplementationsMocker)
+	}
+
+	mocker := NewImplementationsMocker(b.pkgName, iFace)
+	b.cache(mKey, mocker)
+	b.reset2CurPkg()
+	return mocker
+}
+
+// Spy 创建接口真实实现的间谍
+// impl 接口的真实实现, 生成的接口变量默认将所有方法委托给 impl 并记录调用,
+// 需要使用 As 指定接口类型, 比如: mock.Spy(&Impl{}).As(&i).Method("Call").When(1).Return(2)
+// 同一个指针(或 map、chan)类型的 impl 多次调用返回同一个间谍, 其它类型的 impl 每次都创建新的间谍
+func (b *Builder) Spy(impl interface{}) *SpyMocker {
+	var mKey interface{} = &spyKey{typ: reflect.TypeOf(impl)}
+	if v := reflect.ValueOf(impl); v.IsValid() {
+		switch v.Kind() {
+		case reflect.Ptr, reflect.Map, reflect.Chan, reflect.UnsafePointer:
+			mKey = spyKey{typ: v.Type(), ptr: v.Pointer()}
+		}
+	}
+	if mocker, ok := b.mockers[mKey]; ok && !mocker.Canceled() {
+		b.reset2CurPkg()
+		return mocker.(*SpyMocker)
+	}
+
+	mocker := NewSpyMocker(impl)
+	b.cache(mKey, mocker)
+	b.reset2CurPkg()
+	return mocker
+}
+
+// spyKey 间谍的缓存 key, 非指针类型的 impl 使用 key 的指针, 不会和其它间谍冲突
+type spyKey struct {
+	typ reflect.Type
+	ptr uintptr
+}
+
 // cache 添加到缓存
 func (b *Builder) cache(mKey interface{}, cachedMocker Mocker) {
 	b.mockers[mKey] = cachedMocker
 }
 
+// Cached 获取 Cache 缓存的未取消的 Mocker, 供扩展包(比如 sqlmock)在 builder 上复用自定义的 Mocker
+func (b *Builder) Cached(mKey interface{}) (Mocker, bool) {
+	mocker, ok := b.mockers[mKey]
+	if !ok || mocker.Canceled() {
+		return nil, false
+	}
+	return mocker, true
+}
+
+// Cache 缓存扩展包自定义的 Mocker, builder.Reset() 时取消
+// mKey 建议使用扩展包内未导出的类型, 避免和其它 Mocker 冲突
+func (b *Builder) Cache(mKey interface{}, cachedMocker Mocker) {
+	b.cache(mKey, cachedMocker)
+}
+
 // Struct 指定结构体实例
 // 比如需要 mock 结构体函数 (*conn).Write(b []byte)，则 name="conn"
 func (b *Builder) Struct(instance interface{}) *CachedMethodMocker {
 	mKey := reflect.ValueOf(instance).Type().String()
 	if mocker, ok := b.mockers[mKey]; ok && !mocker.Canceled() {
 		b.reset2CurPkg()
-		return mocker.(*CachedMethodMocker)
+		return mocker.(*CachedMethodMocker).withInstance(instance)
 	}
 
 	mocker := NewMethodMocker(b.pkgName, instance)
@@ -157,6 +216,22 @@ func (b *Builder) ExportFunc(name string) *UnexportedFuncMocker {
 	return mocker
 }
 
+// Closure 匿名闭包 mock
+// 比如需要 mock 函数 Handler 中定义的第一个闭包(符号名为 pkg_name.Handler.func1), 则 outer="Handler", index=1
+// 嵌套闭包可以指定外层闭包, 比如 outer="Handler.func1"
+func (b *Builder) Closure(outer string, index int) *ClosureMocker {
+	key := fmt.Sprintf("closure_%s.%s#%d", b.pkgName, outer, index)
+	if mocker, ok := b.mockers[key]; ok && !mocker.Canceled() {
+		b.reset2CurPkg()
+		return mocker.(*ClosureMocker)
+	}
+
+	mocker := NewClosureMocker(b.pkgName, outer, index)
+	b.cache(key, mocker)
+	b.reset2CurPkg()
+	return mocker
+}
+
 // Var 变量 mock, target 类型必须传递指针类型
 func (b *Builder) Var(v interface{}) VarMock {
 	cacheKey := fmt.Sprintf("var_%d", reflect.ValueOf(v).Pointer())
@@ -169,6 +244,40 @@ func (b *Builder) Var(v interface{}) VarMock {
 	return mocker
 }
 
+// VarPath 结构体变量嵌套字段 mock
+// target 结构体变量的指针
+// path 字段路径, 以.分隔, 比如: Limits.MaxConn
+func (b *Builder) VarPath(target interface{}, path string) VarMock {
+	cacheKey := fmt.Sprintf("var_path_%d_%s", reflect.ValueOf(target).Pointer(), path)
+	if mocker, ok := b.mockers[cacheKey]; ok && !mocker.Canceled() {
+		return mocker.(VarMock)
+	}
+
+	mocker := NewVarPathMocker(target, path)
+	b.cache(cacheKey, mocker)
+	return mocker
+}
+
+// MapEntry map 单个键值 mock, Cancel 时恢复原值, mock 前不存在的键会被删除
+// target map 变量或者其指针
+// key 键
+func (b *Builder) MapEntry(target interface{}, key interface{}) *MapEntryMocker {
+	mocker := NewMapEntryMocker(target, key)
+	cacheKey := fmt.Sprintf("map_entry_%d_%#v", mocker.m.Pointer(), key)
+	if cached, ok := b.mockers[cacheKey]; ok && !cached.Canceled() {
+		return cached.(*MapEntryMocker)
+	}
+
+	b.cache(cacheKey, mocker)
+	return mocker
+}
+
+// Slice 切片变量 mock, 通过 Index 指定需要 mock 的元素
+// target 切片变量的指针
+func (b *Builder) Slice(target interface{}) *SliceMocker {
+	return newSliceMocker(b, target)
+}
+
 // UnExportedVar 未导出变量 mock
 // path 变量路径, package + name 组成, 比如 "github.com/xxx/yyy.varName"
 // 变量类型支持:
@@ -191,10 +300,16 @@ func (b *Builder) UnExportedVar(path string) UnExportedVarMock {
 // Reset 取消当前 builder 的所有 Mock
 func (b *Builder) Reset() *Builder {
 	for _, mocker := range b.mockers {
+		if !logger.IsDebugOpen() {
+			mocker.Cancel()
+			continue
+		}
+		// 取消之前获取描述, 变量 mock 的描述包含原值和 mock 值
+		desc := mocker.String()
 		mocker.Cancel()
 		// callerDeps 当前的调用栈栈层次
 		const callerDeps = 5
-		logger.Consolefc(logger.DebugLevel, "mockers [%s] resets.", logger.Caller(callerDeps), mocker.String())
+		logger.Consolefc(logger.DebugLevel, "mockers [%s] resets.", logger.Caller(callerDeps), desc)
 	}
 	return b
 }
@@ -5,16 +5,23 @@
 package mocker
 
 import (
+	"reflect"
 	"strings"
 
+	"github.com/tencent/goom/erro"
 	"github.com/tencent/goom/internal/iface"
+	"github.com/tencent/goom/internal/logger"
 )
 
 // CachedMethodMocker 带缓存的方法 Mocker,将同一个函数或方法的 Mocker 进行 cache
 type CachedMethodMocker struct {
 	*MethodMocker
 	mCache  map[string]*MethodMocker
-	umCache map[string]UnExportedMocker
+	umCache map[string]InferredMocker
+	// instance Struct 指定的结构体实例, 用于 ForInstance
+	instance    interface{}
+	iCache      map[uintptr]*InstanceMocker
+	dispatchers map[string]*instanceDispatcher
 }
 
 // NewCachedMethodMocker 创建新的带缓存的方法 Mocker
@@ -22,7 +29,10 @@ func NewCachedMethodMocker(m *MethodMocker) *CachedMethodMocker {
 	return &CachedMethodMocker{
 		MethodMocker: m,
 		mCache:       make(map[string]*MethodMocker, 16),
-		umCache:      make(map[string]UnExportedMocker, 16),
+		umCache:      make(map[string]InferredMocker, 16),
+		instance:     m.structDef,
+		iCache:       make(map[uintptr]*InstanceMocker, 4),
+		dispatchers:  make(map[string]*instanceDispatcher, 4),
 	}
 }
 
@@ -50,7 +60,7 @@ func (m *CachedMethodMocker) Method(name string) ExportedMocker {
 }
 
 // ExportMethod 导出私有方法
-func (m *CachedMethodMocker) ExportMethod(name string) UnExportedMocker {
+func (m *CachedMethodMocker) ExportMethod(name string) InferredMocker {
 	if mocker, ok := m.umCache[name]; ok && !mocker.Canceled() {
 		return mocker
 	}
@@ -60,6 +70,96 @@ func (m *CachedMethodMocker) ExportMethod(name string) UnExportedMocker {
 	return exportedMocker
 }
 
+// ForInstance 只对接收体为当前指定实例的调用进行 mock, 其它实例的调用将执行原方法
+// 比如: mock.Struct(conn).ForInstance().Method("Write").Return(0, errors.New("closed"))
+// 注意: 同一个方法不能同时使用 ForInstance 和 Method 进行 mock
+func (m *CachedMethodMocker) ForInstance() *InstanceMocker {
+	receiver := reflect.ValueOf(m.instance)
+	if receiver.Kind() != reflect.Ptr || receiver.IsNil() {
+		panic(erro.NewIllegalParamTypeError("instance", receiver.Type().String(), "non-nil ptr"))
+	}
+	if mocker, ok := m.iCache[receiver.Pointer()]; ok {
+		return mocker
+	}
+	mocker := &InstanceMocker{
+		cached:   m,
+		instance: m.instance,
+		mockers:  make(map[string]*InstanceMethodMocker, 4),
+	}
+	m.iCache[receiver.Pointer()] = mocker
+	return mocker
+}
+
+// withInstance 返回指定实例的 Mocker, 实例不同时返回共享缓存的副本, 不修改已经返回的 Mocker 的实例
+func (m *CachedMethodMocker) withInstance(instance interface{}) *CachedMethodMocker {
+	v, cur := reflect.ValueOf(instance), reflect.ValueOf(m.instance)
+	if v.Kind() != reflect.Ptr || (cur.Kind() == reflect.Ptr && cur.Pointer() == v.Pointer()) {
+		return m
+	}
+	view := *m
+	view.instance = instance
+	return &view
+}
+
+// dispatcher 获取方法的接收体分发器, 同一个方法的所有实例共用一个分发器
+func (m *CachedMethodMocker) dispatcher(name string) *instanceDispatcher {
+	if d, ok := m.dispatchers[name]; ok {
+		return d
+	}
+	d := newInstanceDispatcher(m.pkgName, m.MethodMocker.structDef, name)
+	m.dispatchers[name] = d
+	return d
+}
+
+// DelegateTo 将结构体的所有导出方法委托给 fake 对象的同名方法
+// fake 的同名方法参数和返回值类型(不含接收体)必须和原方法一致, 否则不进行委托;
+// 返回未能委托的方法名列表, 委托的方法可以通过 Cancel 一起取消
+func (m *CachedMethodMocker) DelegateTo(fake interface{}) (unmatched []string) {
+	if fake == nil {
+		panic(erro.NewIllegalParamError("fake", "nil"))
+	}
+	fakeV := reflect.ValueOf(fake)
+	sTyp := reflect.TypeOf(m.MethodMocker.structDef)
+	for i := 0; i < sTyp.NumMethod(); i++ {
+		method := sTyp.Method(i)
+		fakeMethod := fakeV.MethodByName(method.Name)
+		if !fakeMethod.IsValid() || !delegatable(method.Type, fakeMethod.Type()) {
+			unmatched = append(unmatched, method.Name)
+			continue
+		}
+		m.Method(method.Name).Apply(reflect.MakeFunc(method.Type, func(args []reflect.Value) []reflect.Value {
+			if fakeMethod.Type().IsVariadic() {
+				return fakeMethod.CallSlice(args[1:])
+			}
+			return fakeMethod.Call(args[1:])
+		}).Interface())
+	}
+	if len(unmatched) > 0 {
+		logger.Consolefc(logger.WarningLevel, "mocker [%s] delegate to %T, unmatched methods: %s",
+			logger.Caller(5), sTyp.String(), fake, strings.Join(unmatched, ", "))
+	}
+	return unmatched
+}
+
+// delegatable 判断方法(含接收体)是否可以委托给 fake 的方法(不含接收体)
+func delegatable(methodTyp, fakeTyp reflect.Type) bool {
+	if methodTyp.NumIn() != fakeTyp.NumIn()+1 || methodTyp.NumOut() != fakeTyp.NumOut() ||
+		methodTyp.IsVariadic() != fakeTyp.IsVariadic() {
+		return false
+	}
+	for i := 0; i < fakeTyp.NumIn(); i++ {
+		if methodTyp.In(i+1) != fakeTyp.In(i) {
+			return false
+		}
+	}
+	for i := 0; i < fakeTyp.NumOut(); i++ {
+		if methodTyp.Out(i) != fakeTyp.Out(i) {
+			return false
+		}
+	}
+	return true
+}
+
 // Cancel 取消 mock
 func (m *CachedMethodMocker) Cancel() {
 	for _, v := range m.mCache {
@@ -68,6 +168,12 @@ func (m *CachedMethodMocker) Cancel() {
 	for _, v := range m.umCache {
 		v.Cancel()
 	}
+	for _, v := range m.iCache {
+		v.Cancel()
+	}
+	for _, v := range m.dispatchers {
+		v.cancel()
+	}
 }
 
 // CachedUnexportedMethodMocker 带缓存的未导出方法 Mocker
@@ -94,7 +200,7 @@ func (m *CachedUnexportedMethodMocker) String() string {
 }
 
 // Method 设置结构体的方法名
-func (m *CachedUnexportedMethodMocker) Method(name string) UnExportedMocker {
+func (m *CachedUnexportedMethodMocker) Method(name string) InferredMocker {
 	if mocker, ok := m.mockers[name]; ok && !mocker.Canceled() {
 		return mocker
 	}
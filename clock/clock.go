@@ -0,0 +1,414 @@
+// Package clock 基于 goom 的函数 mock 实现的虚拟时钟。
+// Freeze 之后 time.Now、time.Since、time.Until 返回虚拟时间,
+// time.Sleep、time.After、time.NewTimer、time.AfterFunc、time.NewTicker 等不再依赖真实时间,
+// 只有调用 Advance 推进虚拟时间时, 到期的定时器才会按照到期时间(相同时按照创建顺序)依次触发, 测试结果是确定的。
+// 注意: 需要在测试时关闭内联: -gcflags="all=-l"
+package clock
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	mocker "github.com/tencent/goom"
+)
+
+// std 当前生效的虚拟时钟
+var std = &virtualClock{}
+
+// maxDuration 底层真实定时器的到期时间, 创建之后立即停止, 不会触发
+const maxDuration time.Duration = 1<<63 - 1
+
+// Freeze 在 builder 上 mock 时间相关的函数, 将虚拟时间冻结在 t
+// 同一个 builder 重复调用时只重置虚拟时间, 之前 pending 的定时器和 Sleep 会被丢弃(Sleep 的协程会被唤醒);
+// builder.Reset() 之后恢复真实时间, 并唤醒所有 Sleep 的协程; 虚拟的定时器和 Ticker 不再触发, 调用 Stop、Reset 不会 panic
+func Freeze(b *mocker.Builder, t time.Time) {
+	std.freeze(b, t)
+}
+
+// Now 当前的虚拟时间
+func Now() time.Time {
+	return std.Now()
+}
+
+// Advance 将虚拟时间推进 d, 并按照到期顺序触发期间到期的定时器、唤醒到期的 Sleep;
+// time.AfterFunc 的回调在 Advance 所在的协程中同步执行
+func Advance(d time.Duration) {
+	std.Advance(d)
+}
+
+// Pending 当前等待触发的定时器、Ticker 和 Sleep 的数量
+func Pending() int {
+	return std.Pending()
+}
+
+// BlockUntil 阻塞直到至少有 n 个等待触发的定时器、Ticker 或 Sleep,
+// 用于在 Advance 之前确认被测协程已经进入 Sleep 或者创建了定时器
+func BlockUntil(n int) {
+	std.BlockUntil(n)
+}
+
+// event 虚拟时钟上等待触发的事件
+type event struct {
+	when time.Time
+	// period Ticker 的周期, 定时器为 0
+	period time.Duration
+	// ch 到期时写入当前时间的通道: 定时器、Ticker 的 C 或者 Sleep 的唤醒通道
+	ch chan time.Time
+	// fn time.AfterFunc 的回调
+	fn func()
+	// sleeper 是否为 Sleep 的唤醒事件, 重新 Freeze 时只唤醒 Sleep, 定时器的通道保持为空
+	sleeper bool
+}
+
+// virtualClock 虚拟时钟
+type virtualClock struct {
+	mu      sync.Mutex
+	changed *sync.Cond
+	now     time.Time
+	// events 按照到期时间排序的事件, 到期时间相同时按照加入的顺序排列
+	events []*event
+	// timers 虚拟定时器到事件的映射, 用于区分真实定时器和虚拟定时器
+	timers  map[*time.Timer]*event
+	tickers map[*time.Ticker]*event
+
+	builder    *mocker.Builder
+	nowMocker  mocker.Mocker
+	newTimerFn func(time.Duration) *time.Timer
+	newTickFn  func(time.Duration) *time.Ticker
+	timerStop  func(*time.Timer) bool
+	timerReset func(*time.Timer, time.Duration) bool
+	tickerStop func(*time.Ticker)
+	tickReset  func(*time.Ticker, time.Duration)
+}
+
+// freeze 重置虚拟时间, 首次调用时 mock 时间相关的函数
+func (c *virtualClock) freeze(b *mocker.Builder, t time.Time) {
+	c.mu.Lock()
+	if c.changed == nil {
+		c.changed = sync.NewCond(&c.mu)
+	}
+	// 唤醒之前的 Sleep, 避免协程永久阻塞
+	c.wakeSleepers(t)
+	c.now = t
+	c.events = nil
+	c.timers = make(map[*time.Timer]*event)
+	c.tickers = make(map[*time.Ticker]*event)
+	patched := c.builder == b && c.nowMocker != nil && !c.nowMocker.Canceled()
+	c.builder = b
+	c.mu.Unlock()
+
+	if !patched {
+		c.patch(b)
+	}
+}
+
+// patch 在 builder 上 mock 时间相关的函数
+func (c *virtualClock) patch(b *mocker.Builder) {
+	// 虚拟定时器底层使用停止的真实定时器, mock 取消之后调用真实的 Stop、Reset 不会 panic
+	c.newTimerFn, c.newTickFn = newTimerTrampoline, newTickerTrampoline
+	nowMocker := b.Func(time.Now)
+	nowMocker.Apply(c.Now)
+	b.Func(time.Since).Apply(func(t time.Time) time.Duration {
+		return c.Now().Sub(t)
+	})
+	b.Func(time.Until).Apply(func(t time.Time) time.Duration {
+		return t.Sub(c.Now())
+	})
+	b.Func(time.Sleep).Apply(c.sleep)
+	b.Func(time.After).Apply(func(d time.Duration) <-chan time.Time {
+		return c.newTimer(d, nil).C
+	})
+	b.Func(time.NewTimer).Origin(&c.newTimerFn).Apply(func(d time.Duration) *time.Timer {
+		return c.newTimer(d, nil)
+	})
+	b.Func(time.AfterFunc).Apply(func(d time.Duration, f func()) *time.Timer {
+		return c.newTimer(d, f)
+	})
+	b.Func(time.NewTicker).Origin(&c.newTickFn).Apply(c.newTicker)
+	b.Func(time.Tick).Apply(func(d time.Duration) <-chan time.Time {
+		if d <= 0 {
+			return nil
+		}
+		return c.newTicker(d).C
+	})
+
+	// 真实的定时器调用原方法, 跳板函数需要指定占位函数
+	c.timerStop, c.timerReset, c.tickerStop, c.tickReset =
+		timerStopTrampoline, timerResetTrampoline, tickerStopTrampoline, tickerResetTrampoline
+	b.Struct(&time.Timer{}).Method("Stop").Origin(&c.timerStop).Apply(c.stopTimer)
+	b.Struct(&time.Timer{}).Method("Reset").Origin(&c.timerReset).Apply(c.resetTimer)
+	b.Struct(&time.Ticker{}).Method("Stop").Origin(&c.tickerStop).Apply(c.stopTicker)
+	b.Struct(&time.Ticker{}).Method("Reset").Origin(&c.tickReset).Apply(c.resetTicker)
+	b.Cache(releaserKey{}, &releaser{clock: c})
+
+	c.mu.Lock()
+	c.nowMocker = nowMocker
+	c.mu.Unlock()
+}
+
+// Now 当前的虚拟时间
+func (c *virtualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Pending 等待触发的事件数量
+func (c *virtualClock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.events)
+}
+
+// BlockUntil 阻塞直到至少有 n 个等待触发的事件
+func (c *virtualClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.changed == nil || len(c.events) < n {
+		if c.changed == nil {
+			c.changed = sync.NewCond(&c.mu)
+		}
+		c.changed.Wait()
+	}
+}
+
+// Advance 推进虚拟时间, 依次触发到期的事件
+func (c *virtualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	target := c.now.Add(d)
+	for len(c.events) > 0 && !c.events[0].when.After(target) {
+		e := c.events[0]
+		c.events = c.events[1:]
+		c.now = e.when
+		if e.period > 0 {
+			e.when = e.when.Add(e.period)
+			c.schedule(e)
+		}
+		if e.fn != nil {
+			c.mu.Unlock()
+			e.fn()
+			c.mu.Lock()
+			continue
+		}
+		// 和真实的 Ticker 一样, 接收方来不及读取时丢弃
+		select {
+		case e.ch <- c.now:
+		default:
+		}
+	}
+	c.now = target
+	c.mu.Unlock()
+}
+
+// wakeSleepers 唤醒并移除所有 Sleep 的事件, 调用方需要持有锁
+func (c *virtualClock) wakeSleepers(t time.Time) {
+	events := c.events[:0]
+	for _, e := range c.events {
+		if e.sleeper {
+			e.ch <- t
+		} else {
+			events = append(events, e)
+		}
+	}
+	c.events = events
+}
+
+// sleep 阻塞直到虚拟时间推进了 d
+func (c *virtualClock) sleep(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	wake := make(chan time.Time, 1)
+	c.mu.Lock()
+	c.schedule(&event{when: c.now.Add(d), ch: wake, sleeper: true})
+	c.mu.Unlock()
+	<-wake
+}
+
+// newTimer 创建虚拟定时器, f 不为空时为 time.AfterFunc 创建的定时器
+func (c *virtualClock) newTimer(d time.Duration, f func()) *time.Timer {
+	e := &event{fn: f}
+	t := c.newTimerFn(maxDuration)
+	c.timerStop(t)
+	t.C = nil
+	if f == nil {
+		e.ch = make(chan time.Time, 1)
+		t.C = e.ch
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e.when = c.now.Add(d)
+	c.timers[t] = e
+	c.schedule(e)
+	return t
+}
+
+// newTicker 创建虚拟 Ticker
+func (c *virtualClock) newTicker(d time.Duration) *time.Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	e := &event{period: d, ch: make(chan time.Time, 1)}
+	t := c.newTickFn(maxDuration)
+	c.tickerStop(t)
+	t.C = e.ch
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e.when = c.now.Add(d)
+	c.tickers[t] = e
+	c.schedule(e)
+	return t
+}
+
+// stopTimer 停止定时器, 返回定时器是否处于等待触发的状态
+func (c *virtualClock) stopTimer(t *time.Timer) bool {
+	c.mu.Lock()
+	e, ok := c.timers[t]
+	if !ok {
+		c.mu.Unlock()
+		return c.timerStop(t)
+	}
+	defer c.mu.Unlock()
+	return c.stop(e)
+}
+
+// resetTimer 重置定时器的到期时间, 返回定时器之前是否处于等待触发的状态
+func (c *virtualClock) resetTimer(t *time.Timer, d time.Duration) bool {
+	c.mu.Lock()
+	e, ok := c.timers[t]
+	if !ok {
+		c.mu.Unlock()
+		return c.timerReset(t, d)
+	}
+	defer c.mu.Unlock()
+	active := c.stop(e)
+	e.when = c.now.Add(d)
+	c.schedule(e)
+	return active
+}
+
+// stopTicker 停止 Ticker
+func (c *virtualClock) stopTicker(t *time.Ticker) {
+	c.mu.Lock()
+	e, ok := c.tickers[t]
+	if !ok {
+		c.mu.Unlock()
+		c.tickerStop(t)
+		return
+	}
+	defer c.mu.Unlock()
+	c.stop(e)
+}
+
+// resetTicker 重置 Ticker 的周期
+func (c *virtualClock) resetTicker(t *time.Ticker, d time.Duration) {
+	if d <= 0 {
+		panic("non-positive interval for Ticker.Reset")
+	}
+	c.mu.Lock()
+	e, ok := c.tickers[t]
+	if !ok {
+		c.mu.Unlock()
+		c.tickReset(t, d)
+		return
+	}
+	defer c.mu.Unlock()
+	c.stop(e)
+	e.period = d
+	e.when = c.now.Add(d)
+	c.schedule(e)
+}
+
+// schedule 按照到期时间和创建顺序插入事件, 调用方需要持有锁
+func (c *virtualClock) schedule(e *event) {
+	i := len(c.events)
+	for i > 0 && c.events[i-1].when.After(e.when) {
+		i--
+	}
+	c.events = append(c.events, nil)
+	copy(c.events[i+1:], c.events[i:])
+	c.events[i] = e
+	if c.changed != nil {
+		c.changed.Broadcast()
+	}
+}
+
+// stop 移除等待触发的事件并清空通道中未读取的值, 返回事件是否处于等待触发的状态, 调用方需要持有锁
+func (c *virtualClock) stop(e *event) bool {
+	if e.ch != nil {
+		select {
+		case <-e.ch:
+		default:
+		}
+	}
+	for i, pending := range c.events {
+		if pending == e {
+			c.events = append(c.events[:i], c.events[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// releaserKey releaser 在 builder 中的缓存 key
+type releaserKey struct{}
+
+// releaser builder.Reset() 时唤醒所有 Sleep 的协程, 避免协程永久阻塞
+type releaser struct {
+	clock    *virtualClock
+	canceled bool
+}
+
+// Apply 不支持
+func (r *releaser) Apply(interface{}) {
+	panic("clock releaser does not support Apply.")
+}
+
+// Cancel 唤醒所有 Sleep 的协程
+func (r *releaser) Cancel() {
+	r.clock.mu.Lock()
+	defer r.clock.mu.Unlock()
+	r.clock.wakeSleepers(r.clock.now)
+	r.canceled = true
+}
+
+// Canceled 是否取消了 mock
+func (r *releaser) Canceled() bool {
+	return r.canceled
+}
+
+// String mock 的名称或描述, 方便调试和问题排查
+func (r *releaser) String() string {
+	return "clock.sleepers"
+}
+
+// 以下为调用原方法的跳板函数占位, mock 之后函数体会被替换, 实际不会执行该函数体, 但是必须编写
+
+func timerStopTrampoline(t *time.Timer) bool {
+	fmt.Println("only for placeholder, will not call", t)
+	return false
+}
+
+func timerResetTrampoline(t *time.Timer, d time.Duration) bool {
+	fmt.Println("only for placeholder, will not call", t, d)
+	return false
+}
+
+func tickerStopTrampoline(t *time.Ticker) {
+	fmt.Println("only for placeholder, will not call", t)
+}
+
+func tickerResetTrampoline(t *time.Ticker, d time.Duration) {
+	fmt.Println("only for placeholder, will not call", t, d)
+}
+
+func newTimerTrampoline(d time.Duration) *time.Timer {
+	fmt.Println("only for placeholder, will not call", d)
+	return nil
+}
+
+func newTickerTrampoline(d time.Duration) *time.Ticker {
+	fmt.Println("only for placeholder, will not call", d)
+	return nil
+}
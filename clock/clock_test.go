@@ -0,0 +1,187 @@
+package clock_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/tencent/goom"
+	"github.com/tencent/goom/clock"
+)
+
+// TestUnitClockTestSuite 测试入口
+func TestUnitClockTestSuite(t *testing.T) {
+	suite.Run(t, new(clockTestSuite))
+}
+
+type clockTestSuite struct {
+	suite.Suite
+	start time.Time
+}
+
+func (s *clockTestSuite) SetupTest() {
+	s.start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// TestUnitFreeze 测试冻结和推进虚拟时间
+func (s *clockTestSuite) TestUnitFreeze() {
+	s.Run("success", func() {
+		mock := mocker.Create()
+		defer mock.Reset()
+		clock.Freeze(mock, s.start)
+		s.Equal(s.start, time.Now(), "now check")
+
+		clock.Advance(time.Hour)
+		s.Equal(s.start.Add(time.Hour), time.Now(), "advance check")
+		s.Equal(time.Hour, time.Since(s.start), "since check")
+		s.Equal(time.Hour, time.Until(s.start.Add(2*time.Hour)), "until check")
+
+		mock.Reset()
+		s.True(time.Now().After(s.start.Add(time.Hour)), "reset check")
+	})
+}
+
+// TestUnitTimers 测试定时器、AfterFunc 和 Ticker 按照到期顺序触发
+func (s *clockTestSuite) TestUnitTimers() {
+	s.Run("success", func() {
+		mock := mocker.Create()
+		defer mock.Reset()
+		clock.Freeze(mock, s.start)
+
+		var fired []string
+		timer := time.NewTimer(2 * time.Second)
+		after := time.After(time.Second)
+		time.AfterFunc(3*time.Second, func() {
+			fired = append(fired, "after-func")
+		})
+		stopped := time.NewTimer(time.Second)
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		s.True(stopped.Stop(), "stop active timer check")
+		s.Equal(4, clock.Pending(), "pending check")
+
+		clock.Advance(time.Second)
+		s.Equal(s.start.Add(time.Second), <-after, "after check")
+		s.Equal(s.start.Add(time.Second), <-ticker.C, "tick check")
+		select {
+		case <-timer.C:
+			s.Fail("timer fired too early")
+		default:
+		}
+
+		clock.Advance(2 * time.Second)
+		s.Equal(s.start.Add(2*time.Second), <-timer.C, "timer check")
+		s.Equal([]string{"after-func"}, fired, "after func check")
+		s.Equal(s.start.Add(2*time.Second), <-ticker.C, "ticker drop check")
+
+		s.False(timer.Reset(time.Second), "reset fired timer check")
+		clock.Advance(time.Second)
+		s.Equal(s.start.Add(4*time.Second), <-timer.C, "timer reset check")
+	})
+}
+
+// TestUnitSleep 测试 Advance 唤醒 Sleep 的协程
+func (s *clockTestSuite) TestUnitSleep() {
+	s.Run("success", func() {
+		mock := mocker.Create()
+		defer mock.Reset()
+		clock.Freeze(mock, s.start)
+
+		var wg sync.WaitGroup
+		woken := make(chan time.Duration, 2)
+		for _, d := range []time.Duration{time.Minute, time.Second} {
+			wg.Add(1)
+			go func(d time.Duration) {
+				defer wg.Done()
+				time.Sleep(d)
+				woken <- d
+			}(d)
+		}
+		clock.BlockUntil(2)
+		clock.Advance(time.Second)
+		s.Equal(time.Second, <-woken, "sleep second check")
+		clock.Advance(time.Minute)
+		s.Equal(time.Minute, <-woken, "sleep minute check")
+		wg.Wait()
+	})
+}
+
+// TestUnitRefreeze 测试重新 Freeze 时唤醒 Sleep, 丢弃的定时器通道保持为空
+func (s *clockTestSuite) TestUnitRefreeze() {
+	s.Run("success", func() {
+		mock := mocker.Create()
+		defer mock.Reset()
+		clock.Freeze(mock, s.start)
+
+		after := time.After(time.Second)
+		timer := time.NewTimer(time.Second)
+		woken := make(chan struct{})
+		go func() {
+			time.Sleep(time.Minute)
+			close(woken)
+		}()
+		clock.BlockUntil(3)
+
+		clock.Freeze(mock, s.start.Add(time.Hour))
+		<-woken
+		s.Equal(0, clock.Pending(), "pending check")
+		select {
+		case <-after:
+			s.Fail("after channel should stay empty after refreeze")
+		case <-timer.C:
+			s.Fail("timer channel should stay empty after refreeze")
+		default:
+		}
+	})
+}
+
+// TestUnitRealTimers 测试冻结前创建的真实定时器仍然调用原方法
+func (s *clockTestSuite) TestUnitRealTimers() {
+	timer := time.NewTimer(time.Hour)
+	ticker := time.NewTicker(time.Hour)
+	mock := mocker.Create()
+	defer mock.Reset()
+	clock.Freeze(mock, s.start)
+
+	s.True(timer.Reset(time.Hour), "real timer reset check")
+	s.True(timer.Stop(), "real timer stop check")
+	s.False(timer.Stop(), "real timer stopped check")
+	ticker.Reset(time.Hour)
+	ticker.Stop()
+	s.Equal(0, clock.Pending(), "pending check")
+}
+
+// TestUnitResetRelease 测试 builder.Reset() 唤醒 Sleep, 虚拟定时器之后调用真实的 Stop、Reset 不会 panic
+func (s *clockTestSuite) TestUnitResetRelease() {
+	mock := mocker.Create()
+	clock.Freeze(mock, s.start)
+
+	timer := time.NewTimer(time.Second)
+	ticker := time.NewTicker(time.Second)
+	woken := make(chan struct{})
+	go func() {
+		time.Sleep(time.Minute)
+		close(woken)
+	}()
+	clock.BlockUntil(3)
+
+	mock.Reset()
+	select {
+	case <-woken:
+	case <-time.After(time.Second):
+		s.Fail("sleeper should be woken after reset")
+	}
+	s.NotPanics(func() {
+		s.False(timer.Stop(), "virtual timer stop check")
+		timer.Reset(time.Millisecond)
+		ticker.Reset(time.Millisecond)
+		ticker.Stop()
+	}, "virtual timer after reset check")
+	select {
+	case <-timer.C:
+		s.Fail("virtual timer should stay inert after reset")
+	case <-time.After(20 * time.Millisecond):
+	}
+	timer.Stop()
+}
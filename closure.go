@@ -0,0 +1,215 @@
+// Package mocker 定义了 mock 的外层用户使用 API 定义,
+// 包括函数、方法、接口、未导出函数(或方法的)的 Mocker 的实现。
+// 当前文件实现了匿名闭包(及嵌套函数)的 mock:
+// 闭包编译后的符号名为 外层函数名.funcN(嵌套闭包为 外层闭包名.N), 按外层函数名和序号解析出闭包符号后进行 mock,
+// 通过 ApplyWithCtx 指定回调时, 闭包上下文(捕获变量所在的对象地址)作为第一个参数传给回调函数, 以便访问捕获变量以及调用原闭包。
+package mocker
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"unsafe"
+
+	"github.com/tencent/goom/erro"
+	"github.com/tencent/goom/internal/logger"
+	"github.com/tencent/goom/internal/proxy"
+	"github.com/tencent/goom/internal/unexports2"
+)
+
+// nestedClosureName 嵌套闭包的外层闭包名称, 其内部闭包的符号名为 外层闭包名.N
+var nestedClosureName = regexp.MustCompile(`\.func\d+(\.\d+)*$`)
+
+// ctxType 闭包上下文参数的类型
+var ctxType = reflect.TypeOf(unsafe.Pointer(nil))
+
+// ClosureMocker 对匿名闭包进行 mock
+type ClosureMocker struct {
+	*baseMocker
+	// name 闭包的符号名称
+	name string
+	// originVar 用户指定的原闭包函数变量的指针
+	originVar interface{}
+}
+
+// NewClosureMocker 创建闭包 Mocker
+// pkgName 包路径
+// outer 外层函数名称, 比如: Handler、(*Server).Serve、init.0, 嵌套闭包可以指定外层闭包, 比如: Handler.func1
+// index 闭包在外层函数中的序号, 从 1 开始
+func NewClosureMocker(pkgName string, outer string, index int) *ClosureMocker {
+	return &ClosureMocker{
+		baseMocker: newBaseMocker(pkgName),
+		name:       resolveClosure(pkgName+"."+outer, index),
+	}
+}
+
+// resolveClosure 根据外层函数名和序号解析闭包的符号名称
+func resolveClosure(outer string, index int) string {
+	if index <= 0 {
+		panic(erro.NewIllegalParamError("index", strconv.Itoa(index)))
+	}
+	candidates := []string{outer + ".func" + strconv.Itoa(index)}
+	if nestedClosureName.MatchString(outer) {
+		candidates = append([]string{outer + "." + strconv.Itoa(index)}, candidates...)
+	}
+	for _, name := range candidates {
+		if _, err := unexports2.FindFuncByName(name); err == nil {
+			return name
+		}
+	}
+
+	var suggestions []string
+	if syms, err := unexports2.FunctionSymbolsWithPrefix(outer + "."); err == nil {
+		for _, s := range syms {
+			suggestions = append(suggestions, s.Name)
+		}
+	}
+	panic(erro.NewFuncNotFoundErrorWithSuggestion(candidates[0], suggestions))
+}
+
+// String mock 的名称或描述, 方便调试和问题排查
+func (m *ClosureMocker) String() string {
+	return m.name
+}
+
+// Apply 指定 mock 执行的回调函数, 回调函数的签名需要和闭包保持一致
+func (m *ClosureMocker) Apply(callback interface{}) {
+	m.when = nil
+	m.doApply(callback, false)
+}
+
+// ApplyWithCtx 指定 mock 执行的回调函数, 回调函数的第一个参数为闭包上下文,
+// 其余参数和返回值与闭包保持一致, 比如: func(ctx unsafe.Pointer, i int) int
+// 闭包上下文指向闭包对象, 其第一个字段为函数地址, 之后依次为捕获的变量(按引用捕获的变量为其指针);
+// 闭包上下文是每次调用时传入的, 多个协程并发调用或者递归调用时互不影响
+func (m *ClosureMocker) ApplyWithCtx(callback interface{}) {
+	typ := reflect.TypeOf(callback)
+	if typ == nil || typ.Kind() != reflect.Func || typ.NumIn() == 0 || typ.In(0) != ctxType {
+		panic(erro.NewIllegalParamTypeError("callback", fmt.Sprintf("%T", callback), "func(unsafe.Pointer, ...)"))
+	}
+	m.when = nil
+	m.doApply(callback, true)
+}
+
+// As 指定闭包的函数签名, 有调试信息时可以不指定
+func (m *ClosureMocker) As(funcDef interface{}) *ClosureMocker {
+	checkSignature(m.name, funcDef)
+	m.funcDef = funcDef
+	return m
+}
+
+// When 指定条件匹配
+func (m *ClosureMocker) When(specArg ...interface{}) *When {
+	if m.when != nil {
+		return m.when.When(specArg...)
+	}
+	when, err := CreateWhen(m, m.signature(), specArg, nil, false)
+	if err != nil {
+		panic(err)
+	}
+	if err := m.whens(when); err != nil {
+		panic(err)
+	}
+	m.doApply(m.imp, false)
+	return when
+}
+
+// Return 指定返回值
+func (m *ClosureMocker) Return(value ...interface{}) *When {
+	if m.when != nil {
+		return m.when.Return(value...)
+	}
+	when, err := CreateWhen(m, m.signature(), nil, value, false)
+	if err != nil {
+		panic(err)
+	}
+	if err := m.whens(when); err != nil {
+		panic(err)
+	}
+	m.doApply(m.imp, false)
+	return when
+}
+
+// Returns 依次按顺序返回值
+func (m *ClosureMocker) Returns(values ...interface{}) *When {
+	if m.when != nil {
+		return m.when.Returns(values...)
+	}
+	when, err := CreateWhen(m, m.signature(), nil, nil, false)
+	if err != nil {
+		panic(err)
+	}
+	if err := m.whens(when); err != nil {
+		panic(err)
+	}
+	m.when.Returns(values...)
+	m.doApply(m.imp, false)
+	return when
+}
+
+// Origin 指定调用的原闭包, originFunc 必须是和 ApplyWithCtx 回调函数类型相同的函数变量的指针,
+// 比如: *func(ctx unsafe.Pointer, i int) int, 调用时传入回调函数收到的闭包上下文
+// 跳板函数由框架动态生成, 调用时会从第一个参数恢复闭包上下文
+func (m *ClosureMocker) Origin(originFunc interface{}) ExportedMocker {
+	if v := reflect.ValueOf(originFunc); v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Func {
+		panic(erro.NewIllegalParamTypeError("originFunc", fmt.Sprintf("%T", originFunc), "*func"))
+	}
+	m.originVar = originFunc
+	return m
+}
+
+// signature 获取闭包的函数定义, 未通过 As 指定时从调试信息中推导
+func (m *ClosureMocker) signature() interface{} {
+	if m.funcDef == nil {
+		m.funcDef = inferFuncDef(m.name)
+	}
+	return m.funcDef
+}
+
+// doApply 应用 mock
+// withCtx 为 true 时 imp 的第一个参数为闭包上下文
+func (m *ClosureMocker) doApply(imp interface{}, withCtx bool) {
+	funcTyp := reflect.TypeOf(imp)
+	if withCtx {
+		funcTyp = withoutCtx(funcTyp)
+	}
+	if m.originVar != nil {
+		if !withCtx {
+			panic(erro.NewIllegalStatusError(m.name, "origin requires ApplyWithCtx"))
+		}
+		if originTyp := reflect.TypeOf(m.originVar).Elem(); originTyp != reflect.TypeOf(imp) {
+			panic(erro.NewIllegalParamTypeError("originFunc", originTyp.String(), reflect.TypeOf(imp).String()))
+		}
+	}
+	if m.guard != nil {
+		m.guard.Cancel()
+	}
+	imp, _ = interceptDebugInfo(imp, nil, m)
+	guard, origin, err := proxy.Closure(m.name, imp, funcTyp, withCtx)
+	if err != nil {
+		panic(fmt.Sprintf("proxy closure error: %v", err))
+	}
+	if m.originVar != nil {
+		reflect.ValueOf(m.originVar).Elem().Set(reflect.ValueOf(origin).Elem())
+	}
+
+	m.guard = newPatchMockGuard(guard)
+	m.guard.Apply()
+	m.imp = imp
+	m.canceled = false
+	logger.Consolefc(logger.DebugLevel, "mocker [%s] apply.", logger.Caller(6), m.String())
+}
+
+// withoutCtx 去掉第一个闭包上下文参数, 得到闭包的函数类型
+func withoutCtx(typ reflect.Type) reflect.Type {
+	in := make([]reflect.Type, 0, typ.NumIn()-1)
+	for i := 1; i < typ.NumIn(); i++ {
+		in = append(in, typ.In(i))
+	}
+	out := make([]reflect.Type, 0, typ.NumOut())
+	for i := 0; i < typ.NumOut(); i++ {
+		out = append(out, typ.Out(i))
+	}
+	return reflect.FuncOf(in, out, typ.IsVariadic())
+}
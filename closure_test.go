@@ -0,0 +1,137 @@
+// Package mocker_test 对 mocker 包的测试
+// 当前文件实现了对 closure.go 的单测
+package mocker_test
+
+import (
+	"sync"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/suite"
+	mocker "github.com/tencent/goom"
+	"github.com/tencent/goom/test"
+)
+
+// TestUnitClosureTestSuite 测试入口
+func TestUnitClosureTestSuite(t *testing.T) {
+	suite.Run(t, new(closureMockerTestSuite))
+}
+
+type closureMockerTestSuite struct {
+	suite.Suite
+}
+
+// TestUnitClosureApply 测试闭包 mock apply
+func (s *closureMockerTestSuite) TestUnitClosureApply() {
+	s.Run("success", func() {
+		mock := mocker.Create()
+		mock.Pkg("github.com/tencent/goom/test").Closure("Adder", 1).Apply(func(i int) int {
+			return i * 10
+		})
+		s.Equal(20, test.Adder(1)(2), "closure mock check")
+
+		mock.Reset()
+		s.Equal(3, test.Adder(1)(2), "closure mock reset check")
+	})
+}
+
+// TestUnitClosureReturn 测试闭包 mock return
+func (s *closureMockerTestSuite) TestUnitClosureReturn() {
+	s.Run("success", func() {
+		mock := mocker.Create()
+		mock.Pkg("github.com/tencent/goom/test").Closure("Adder", 1).As(func(i int) int {
+			return 0
+		}).When(2).Return(100)
+		s.Equal(100, test.Adder(1)(2), "closure mock check")
+
+		mock.Reset()
+		s.Equal(3, test.Adder(1)(2), "closure mock reset check")
+	})
+}
+
+// TestUnitClosureCtx 测试闭包 mock 访问捕获变量和调用原闭包
+func (s *closureMockerTestSuite) TestUnitClosureCtx() {
+	s.Run("success", func() {
+		mock := mocker.Create()
+		var origin func(ctx unsafe.Pointer, i int) int
+		closure := mock.Pkg("github.com/tencent/goom/test").Closure("Adder", 1)
+		closure.Origin(&origin)
+		closure.ApplyWithCtx(func(ctx unsafe.Pointer, i int) int {
+			// 闭包对象的第一个字段为函数地址, 第二个字段为捕获的 base
+			base := *(*int)(unsafe.Pointer(uintptr(ctx) + unsafe.Sizeof(uintptr(0))))
+			return base*100 + origin(ctx, i)
+		})
+		s.Equal(305, test.Adder(3)(2), "closure ctx check")
+
+		mock.Reset()
+		s.Equal(5, test.Adder(3)(2), "closure mock reset check")
+	})
+}
+
+// TestUnitClosureRepeat 测试反复 mock 和取消闭包时复用桩函数空间
+func (s *closureMockerTestSuite) TestUnitClosureRepeat() {
+	s.Run("success", func() {
+		for i := 0; i < 100; i++ {
+			mock := mocker.Create()
+			var origin func(ctx unsafe.Pointer, i int) int
+			closure := mock.Pkg("github.com/tencent/goom/test").Closure("Adder", 1)
+			closure.Origin(&origin)
+			closure.ApplyWithCtx(func(ctx unsafe.Pointer, i int) int {
+				return 10 + origin(ctx, i)
+			})
+			s.Equal(13, test.Adder(1)(2), "closure mock check")
+			mock.Reset()
+			s.Equal(3, test.Adder(1)(2), "closure mock reset check")
+		}
+	})
+}
+
+// TestUnitClosureCtxConcurrent 测试并发调用和递归调用时闭包上下文互不影响
+func (s *closureMockerTestSuite) TestUnitClosureCtxConcurrent() {
+	s.Run("success", func() {
+		mock := mocker.Create()
+		defer mock.Reset()
+		var origin func(ctx unsafe.Pointer, i int) int
+		closure := mock.Pkg("github.com/tencent/goom/test").Closure("Adder", 1)
+		closure.Origin(&origin)
+		closure.ApplyWithCtx(func(ctx unsafe.Pointer, i int) int {
+			if i > 0 {
+				// 回调中调用其它闭包对象, 不影响当前调用的闭包上下文
+				test.Adder(-1)(0)
+			}
+			return origin(ctx, i)
+		})
+
+		var wg sync.WaitGroup
+		results := make([]int, 8)
+		for g := range results {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				adder := test.Adder(g * 1000)
+				for i := 1; i <= 1000; i++ {
+					if adder(i) != g*1000+i {
+						results[g]++
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+		s.Equal(make([]int, 8), results, "closure ctx concurrent check")
+	})
+}
+
+// TestUnitClosureOriginWithoutCtx 测试未使用 ApplyWithCtx 时不能指定原闭包
+func (s *closureMockerTestSuite) TestUnitClosureOriginWithoutCtx() {
+	s.Run("success", func() {
+		mock := mocker.Create()
+		defer mock.Reset()
+		var origin func(ctx unsafe.Pointer, i int) int
+		closure := mock.Pkg("github.com/tencent/goom/test").Closure("Adder", 1)
+		closure.Origin(&origin)
+		s.Panics(func() {
+			closure.Apply(func(i int) int { return i })
+		}, "origin without ctx check")
+		s.Equal(3, test.Adder(1)(2), "closure not applied check")
+	})
+}
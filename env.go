@@ -0,0 +1,304 @@
+// Package mocker 定义了 mock 的外层用户使用 API 定义,
+// 包括函数、方法、接口、未导出函数(或方法的)的 Mocker 的实现。
+// 当前文件实现了环境变量的 mock: 通过 mock os.Getenv、os.LookupEnv、os.Environ、syscall.Getenv,
+// 优先从内存中的覆盖层读取环境变量, 不修改真实的进程环境变量。
+// 所有 builder 共享同一份 patch, 第一个覆盖层创建时 patch, 最后一个覆盖层取消时恢复;
+// 多个覆盖层同时生效时, 绑定当前协程的覆盖层优先, 其次是对所有协程生效的覆盖层, 同类覆盖层后创建的优先,
+// 因此并行的测试设置同一个变量时需要使用 CurrentGoroutine 隔离。
+package mocker
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/tencent/goom/internal/hack"
+	"github.com/tencent/goom/internal/logger"
+)
+
+// envCacheKey 环境变量 mock 在 Builder 中的缓存 key
+const envCacheKey = "env"
+
+// envShared 所有 builder 共享的环境变量函数 patch
+var envShared = &envPatch{}
+
+// envPatch 环境变量函数的共享 patch, 按照已注册的覆盖层数量引用计数
+type envPatch struct {
+	// patchLock 保护 patch 和取消 patch 的过程
+	patchLock sync.Mutex
+	// builder patch 使用的内部 builder, 为 nil 表示未 patch
+	builder *Builder
+
+	lock sync.RWMutex
+	// overlays 已注册的覆盖层, 按照创建顺序排列
+	overlays []*EnvMocker
+
+	// 调用原函数的跳板函数
+	getenv        func(key string) string
+	lookupEnv     func(key string) (string, bool)
+	syscallGetenv func(key string) (string, bool)
+}
+
+// EnvMocker 环境变量 mock
+type EnvMocker struct {
+	lock sync.RWMutex
+	// overlay 覆盖层, 值为 nil 表示变量被删除
+	overlay map[string]*string
+	// goroutine 生效的协程 ID, 为 0 时对所有协程生效
+	goroutine int64
+	canceled  bool
+}
+
+// Env 创建环境变量 mock, 同一个 builder 多次调用返回同一个覆盖层
+// 覆盖层中没有的变量从其它生效的覆盖层或真实的进程环境变量中读取; builder.Reset() 之后恢复
+func Env(b *Builder) *EnvMocker {
+	if mocker, ok := b.mockers[envCacheKey]; ok && !mocker.Canceled() {
+		return mocker.(*EnvMocker)
+	}
+
+	m := &EnvMocker{overlay: make(map[string]*string)}
+	envShared.register(m)
+
+	b.cache(envCacheKey, m)
+	logger.Consolefc(logger.DebugLevel, "mocker [%s] apply.", logger.Caller(5), m.String())
+	return m
+}
+
+// Set 设置环境变量
+func (m *EnvMocker) Set(key, value string) *EnvMocker {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.overlay[key] = &value
+	return m
+}
+
+// Unset 删除环境变量, 即使真实的进程环境变量中存在也读取不到
+func (m *EnvMocker) Unset(key string) *EnvMocker {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.overlay[key] = nil
+	return m
+}
+
+// CurrentGoroutine 覆盖层只对调用该方法的协程生效, 其它协程读取不到该覆盖层中的变量
+func (m *EnvMocker) CurrentGoroutine() *EnvMocker {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.goroutine = hack.GoroutineID()
+	return m
+}
+
+// Apply 不支持, 请使用 Set 和 Unset
+func (m *EnvMocker) Apply(interface{}) {
+	panic("EnvMocker does not support Apply, use Set or Unset instead.")
+}
+
+// Cancel 取消 mock, 移除覆盖层; 没有其它覆盖层时恢复读取真实的进程环境变量
+func (m *EnvMocker) Cancel() {
+	if m.canceled {
+		return
+	}
+	envShared.unregister(m)
+	m.canceled = true
+}
+
+// Canceled 是否取消了 mock
+func (m *EnvMocker) Canceled() bool {
+	return m.canceled
+}
+
+// String mock 的名称或描述, 方便调试和问题排查
+func (m *EnvMocker) String() string {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	keys := make([]string, 0, len(m.overlay))
+	for k, v := range m.overlay {
+		if v == nil {
+			keys = append(keys, "-"+k)
+		} else {
+			keys = append(keys, k+"="+*v)
+		}
+	}
+	sort.Strings(keys)
+	return fmt.Sprintf("env[%s]", strings.Join(keys, ","))
+}
+
+// boundGoroutine 覆盖层绑定的协程 ID
+func (m *EnvMocker) boundGoroutine() int64 {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.goroutine
+}
+
+// lookup 从覆盖层中查找环境变量, found 为 false 时需要继续查找
+func (m *EnvMocker) lookup(key string) (value string, ok bool, found bool) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	v, found := m.overlay[key]
+	if !found || v == nil {
+		return "", false, found
+	}
+	return *v, true, true
+}
+
+// register 注册覆盖层, 第一个覆盖层注册时 patch 环境变量函数
+func (p *envPatch) register(m *EnvMocker) {
+	p.patchLock.Lock()
+	defer p.patchLock.Unlock()
+	if p.builder == nil {
+		p.apply()
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.overlays = append(p.overlays, m)
+}
+
+// unregister 移除覆盖层, 最后一个覆盖层移除时取消 patch
+func (p *envPatch) unregister(m *EnvMocker) {
+	p.patchLock.Lock()
+	defer p.patchLock.Unlock()
+	p.lock.Lock()
+	for i, o := range p.overlays {
+		if o == m {
+			p.overlays = append(p.overlays[:i:i], p.overlays[i+1:]...)
+			break
+		}
+	}
+	remain := len(p.overlays)
+	p.lock.Unlock()
+	if remain == 0 && p.builder != nil {
+		p.builder.Reset()
+		p.builder = nil
+	}
+}
+
+// apply patch 环境变量函数
+func (p *envPatch) apply() {
+	b := Create()
+	p.getenv = getenvTrampoline
+	p.lookupEnv = lookupEnvTrampoline
+	p.syscallGetenv = syscallGetenvTrampoline
+	b.Func(os.Getenv).Origin(&p.getenv).Apply(p.doGetenv)
+	b.Func(os.LookupEnv).Origin(&p.lookupEnv).Apply(p.doLookupEnv)
+	// os.Environ 的函数体过短, 无法构造跳板函数, 直接读取 syscall.Environ
+	b.Func(os.Environ).Apply(p.doEnviron)
+	b.Func(syscall.Getenv).Origin(&p.syscallGetenv).Apply(p.doSyscallGetenv)
+	p.builder = b
+}
+
+// active 对当前协程生效的覆盖层, 按照优先级从高到低排列:
+// 绑定当前协程的覆盖层优先, 其次是对所有协程生效的覆盖层, 同类覆盖层后创建的优先
+func (p *envPatch) active() []*EnvMocker {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	var bound, global []*EnvMocker
+	var gid int64
+	for i := len(p.overlays) - 1; i >= 0; i-- {
+		m := p.overlays[i]
+		g := m.boundGoroutine()
+		if g == 0 {
+			global = append(global, m)
+			continue
+		}
+		// 存在绑定协程的覆盖层时才获取当前协程 ID
+		if gid == 0 {
+			gid = hack.GoroutineID()
+		}
+		if g == gid {
+			bound = append(bound, m)
+		}
+	}
+	return append(bound, global...)
+}
+
+// lookup 按照优先级从覆盖层中查找环境变量, found 为 false 时需要从真实的环境变量中读取
+func (p *envPatch) lookup(key string) (value string, ok bool, found bool) {
+	for _, m := range p.active() {
+		if value, ok, found = m.lookup(key); found {
+			return
+		}
+	}
+	return "", false, false
+}
+
+func (p *envPatch) doGetenv(key string) string {
+	if value, _, found := p.lookup(key); found {
+		return value
+	}
+	return p.getenv(key)
+}
+
+func (p *envPatch) doLookupEnv(key string) (string, bool) {
+	if value, ok, found := p.lookup(key); found {
+		return value, ok
+	}
+	return p.lookupEnv(key)
+}
+
+func (p *envPatch) doSyscallGetenv(key string) (string, bool) {
+	if value, ok, found := p.lookup(key); found {
+		return value, ok
+	}
+	return p.syscallGetenv(key)
+}
+
+// doEnviron 将生效的覆盖层合并到真实的环境变量列表中
+func (p *envPatch) doEnviron() []string {
+	env := syscall.Environ()
+	overlays := p.active()
+	if len(overlays) == 0 {
+		return env
+	}
+	// 从低优先级到高优先级合并, 高优先级的覆盖层覆盖低优先级的
+	merged := make(map[string]*string)
+	for i := len(overlays) - 1; i >= 0; i-- {
+		m := overlays[i]
+		m.lock.RLock()
+		for k, v := range m.overlay {
+			merged[k] = v
+		}
+		m.lock.RUnlock()
+	}
+
+	result := make([]string, 0, len(env)+len(merged))
+	for _, kv := range env {
+		key := kv
+		if i := strings.IndexByte(kv, '='); i > 0 {
+			key = kv[:i]
+		}
+		if _, ok := merged[key]; !ok {
+			result = append(result, kv)
+		}
+	}
+	keys := make([]string, 0, len(merged))
+	for k, v := range merged {
+		if v != nil {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		result = append(result, k+"="+*merged[k])
+	}
+	return result
+}
+
+// 以下为调用原函数的跳板函数占位, mock 之后函数体会被替换, 实际不会执行该函数体, 但是必须编写
+
+func getenvTrampoline(key string) string {
+	fmt.Println("only for placeholder, will not call", key)
+	return ""
+}
+
+func lookupEnvTrampoline(key string) (string, bool) {
+	fmt.Println("only for placeholder, will not call", key)
+	return "", false
+}
+
+func syscallGetenvTrampoline(key string) (string, bool) {
+	fmt.Println("only for placeholder, will not call", key)
+	return "", false
+}
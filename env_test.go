@@ -0,0 +1,112 @@
+package mocker_test
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/tencent/goom"
+)
+
+// TestUnitEnvTestSuite 测试入口
+func TestUnitEnvTestSuite(t *testing.T) {
+	suite.Run(t, new(envTestSuite))
+}
+
+type envTestSuite struct {
+	suite.Suite
+}
+
+// TestUnitEnv 测试环境变量覆盖层
+func (s *envTestSuite) TestUnitEnv() {
+	s.Require().NoError(os.Setenv("GOOM_ENV_REAL", "real"))
+	s.Require().NoError(os.Setenv("GOOM_ENV_UNSET", "real"))
+	defer os.Unsetenv("GOOM_ENV_REAL")
+	defer os.Unsetenv("GOOM_ENV_UNSET")
+
+	mock := mocker.Create()
+	mocker.Env(mock).Set("GOOM_ENV_MOCK", "mock").Unset("GOOM_ENV_UNSET")
+
+	s.Equal("mock", os.Getenv("GOOM_ENV_MOCK"), "Getenv mock check")
+	s.Equal("real", os.Getenv("GOOM_ENV_REAL"), "Getenv fallback check")
+	s.Equal("", os.Getenv("GOOM_ENV_UNSET"), "Getenv unset check")
+
+	v, ok := os.LookupEnv("GOOM_ENV_MOCK")
+	s.True(ok, "LookupEnv mock check")
+	s.Equal("mock", v, "LookupEnv mock check")
+	_, ok = os.LookupEnv("GOOM_ENV_UNSET")
+	s.False(ok, "LookupEnv unset check")
+
+	v, ok = syscall.Getenv("GOOM_ENV_MOCK")
+	s.True(ok, "syscall.Getenv mock check")
+	s.Equal("mock", v, "syscall.Getenv mock check")
+
+	env := os.Environ()
+	s.Contains(env, "GOOM_ENV_MOCK=mock", "Environ mock check")
+	s.Contains(env, "GOOM_ENV_REAL=real", "Environ fallback check")
+	s.NotContains(env, "GOOM_ENV_UNSET=real", "Environ unset check")
+
+	s.Same(mocker.Env(mock), mocker.Env(mock), "cache check")
+
+	mock.Reset()
+	s.Equal("", os.Getenv("GOOM_ENV_MOCK"), "reset check")
+	s.Equal("real", os.Getenv("GOOM_ENV_UNSET"), "reset check")
+	_, ok = os.LookupEnv("GOOM_ENV_MOCK")
+	s.False(ok, "reset check")
+}
+
+// TestUnitEnvCurrentGoroutine 测试只对当前协程生效的环境变量覆盖层
+func (s *envTestSuite) TestUnitEnvCurrentGoroutine() {
+	mock := mocker.Create()
+	defer mock.Reset()
+	mocker.Env(mock).CurrentGoroutine().Set("GOOM_ENV_MOCK", "mock")
+
+	s.Equal("mock", os.Getenv("GOOM_ENV_MOCK"), "current goroutine check")
+	done := make(chan string)
+	go func() {
+		done <- os.Getenv("GOOM_ENV_MOCK")
+	}()
+	s.Equal("", <-done, "other goroutine check")
+}
+
+// TestUnitEnvMultiBuilder 测试多个 builder 的覆盖层同时生效, 取消其中一个不影响其它覆盖层
+func (s *envTestSuite) TestUnitEnvMultiBuilder() {
+	mock1, mock2 := mocker.Create(), mocker.Create()
+	defer mock2.Reset()
+	mocker.Env(mock1).Set("GOOM_ENV_A", "a1").Set("GOOM_ENV_SHARED", "1")
+	mocker.Env(mock2).Set("GOOM_ENV_B", "b2").Set("GOOM_ENV_SHARED", "2")
+
+	s.Equal("a1", os.Getenv("GOOM_ENV_A"), "first overlay check")
+	s.Equal("b2", os.Getenv("GOOM_ENV_B"), "second overlay check")
+	s.Equal("2", os.Getenv("GOOM_ENV_SHARED"), "later overlay priority check")
+	env := os.Environ()
+	s.Contains(env, "GOOM_ENV_A=a1", "Environ merge check")
+	s.Contains(env, "GOOM_ENV_SHARED=2", "Environ priority check")
+	s.NotContains(env, "GOOM_ENV_SHARED=1", "Environ priority check")
+
+	mock1.Reset()
+	s.Equal("", os.Getenv("GOOM_ENV_A"), "reset check")
+	s.Equal("b2", os.Getenv("GOOM_ENV_B"), "other overlay kept check")
+	s.Equal("2", os.Getenv("GOOM_ENV_SHARED"), "other overlay kept check")
+
+	mock2.Reset()
+	_, ok := os.LookupEnv("GOOM_ENV_B")
+	s.False(ok, "all reset check")
+}
+
+// TestUnitEnvCurrentGoroutinePriority 测试绑定当前协程的覆盖层优先于对所有协程生效的覆盖层
+func (s *envTestSuite) TestUnitEnvCurrentGoroutinePriority() {
+	bound, global := mocker.Create(), mocker.Create()
+	defer bound.Reset()
+	defer global.Reset()
+	mocker.Env(bound).CurrentGoroutine().Set("GOOM_ENV_MOCK", "bound")
+	mocker.Env(global).Set("GOOM_ENV_MOCK", "global")
+
+	s.Equal("bound", os.Getenv("GOOM_ENV_MOCK"), "current goroutine priority check")
+	done := make(chan string)
+	go func() {
+		done <- os.Getenv("GOOM_ENV_MOCK")
+	}()
+	s.Equal("global", <-done, "other goroutine check")
+}
@@ -2,6 +2,7 @@ package erro
 
 import (
 	"strings"
+	"sync"
 
 	"github.com/tencent/goom/internal/logger"
 )
@@ -12,10 +13,19 @@ const prefix = "func not found: "
 type FuncNotFound struct {
 	funcName    string
 	suggestions []string
+	// candidates 获取候选名称, 第一次调用 Error 时才获取并排序为 suggestions,
+	// 以免探测性的查找(错误会被忽略)每次都计算相似度
+	candidates func() []string
+	once       sync.Once
 }
 
 // Error 返回错误字符串
 func (e *FuncNotFound) Error() string {
+	e.once.Do(func() {
+		if e.candidates != nil {
+			e.suggestions = RankSuggestions(e.funcName, e.candidates(), maxSuggestions)
+		}
+	})
 	msg := prefix + e.funcName
 	if e.suggestions == nil {
 		return msg
@@ -45,6 +55,16 @@ func NewFuncNotFoundError(funcName string) error {
 
 // NewFuncNotFoundErrorWithSuggestion 函数未找到并给出提示
 // funcName 函数名称
+// suggestions 候选名称, 按照和函数名称的相似度排序后给出最相似的几个
 func NewFuncNotFoundErrorWithSuggestion(funcName string, suggestions []string) error {
-	return &FuncNotFound{funcName: funcName, suggestions: suggestions}
+	return NewFuncNotFoundErrorWithCandidates(funcName, func() []string {
+		return suggestions
+	})
+}
+
+// NewFuncNotFoundErrorWithCandidates 函数未找到并给出提示, 候选名称在错误信息输出时才获取
+// funcName 函数名称
+// candidates 获取候选名称, 按照和函数名称的相似度排序后给出最相似的几个
+func NewFuncNotFoundErrorWithCandidates(funcName string, candidates func() []string) error {
+	return &FuncNotFound{funcName: funcName, candidates: candidates}
 }
@@ -0,0 +1,24 @@
+package erro
+
+import "fmt"
+
+// LayoutNotMatch 结构体内存布局不匹配异常
+type LayoutNotMatch struct {
+	typName  string
+	fakeName string
+	reason   string
+}
+
+// Error 返回错误字符串
+func (i *LayoutNotMatch) Error() string {
+	return fmt.Sprintf("layout not match of fake struct %s, expect the same as %s: %s",
+		i.fakeName, i.typName, i.reason)
+}
+
+// NewLayoutNotMatchError 创建结构体内存布局不匹配异常
+// typName 原结构体类型名
+// fakeName fake 结构体类型名
+// reason 不匹配的原因
+func NewLayoutNotMatchError(typName string, fakeName string, reason string) error {
+	return &LayoutNotMatch{typName: typName, fakeName: fakeName, reason: reason}
+}
@@ -8,6 +8,9 @@ var GcFlags = NewRequiredBuildFlagsError("-gcflags=\"all=-l\"")
 // LdFlags 编译标记,取消符号压缩
 var LdFlags = NewRequiredBuildFlagsError("-ld=flags=\"-s=false\"")
 
+// DwarfFlags 编译标记,保留调试信息(go test 默认不生成 DWARF)
+var DwarfFlags = NewRequiredBuildFlagsError("-ldflags=\"-w=false\"")
+
 // RequiredBuildFlags 编译标记未找到
 // 典型的比如: -gcflags="all=-l", -ldflags="-s=false"
 type RequiredBuildFlags struct {
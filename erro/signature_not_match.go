@@ -0,0 +1,26 @@
+package erro
+
+import "fmt"
+
+// SignatureNotMatch 函数签名不匹配异常
+type SignatureNotMatch struct {
+	funcName  string
+	signature string
+	expect    string
+	reason    string
+}
+
+// Error 返回错误字符串
+func (i *SignatureNotMatch) Error() string {
+	return fmt.Sprintf("signature not match of func %s: %s, expect(debug info): %s, %s",
+		i.funcName, i.signature, i.expect, i.reason)
+}
+
+// NewSignatureNotMatchError 创建函数签名不匹配异常
+// funcName 函数名
+// signature 模板函数签名
+// expect 调试信息中的函数签名
+// reason 不匹配的原因
+func NewSignatureNotMatchError(funcName string, signature, expect string, reason string) error {
+	return &SignatureNotMatch{funcName: funcName, signature: signature, expect: expect, reason: reason}
+}
@@ -0,0 +1,97 @@
+package erro
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxSuggestions 最多给出的提示个数
+const maxSuggestions = 5
+
+// RankSuggestions 按照和 name 的相似度对候选名称排序, 返回最相似的 limit 个
+// 优先比较最后一级名称(比如方法名), 再比较全名; limit <= 0 时不限制个数
+func RankSuggestions(name string, candidates []string, limit int) []string {
+	type scored struct {
+		name        string
+		base, whole int
+	}
+	base := strings.ToLower(lastSegment(name))
+	list := make([]scored, 0, len(candidates))
+	for _, c := range candidates {
+		if c == "" {
+			continue
+		}
+		list = append(list, scored{
+			name:  c,
+			base:  similarity(base, strings.ToLower(lastSegment(c))),
+			whole: similarity(strings.ToLower(name), strings.ToLower(c)),
+		})
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].base != list[j].base {
+			return list[i].base < list[j].base
+		}
+		return list[i].whole < list[j].whole
+	})
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	result := make([]string, 0, len(list))
+	for _, v := range list {
+		result = append(result, v.name)
+	}
+	return result
+}
+
+// lastSegment 获取名称的最后一级, 比如 pkg.(*struct).method 返回 method
+func lastSegment(name string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// similarity 计算两个名称的差异度, 值越小越相似
+// 包含关系视为比较相似, 其余情况使用编辑距离
+func similarity(a, b string) int {
+	if a == b {
+		return 0
+	}
+	if strings.Contains(b, a) || strings.Contains(a, b) {
+		return 1
+	}
+	return 1 + levenshtein(a, b)
+}
+
+// levenshtein 计算编辑距离
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// min3 三个数的最小值
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
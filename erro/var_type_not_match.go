@@ -0,0 +1,26 @@
+package erro
+
+import "fmt"
+
+// VarTypeNotMatch 变量值类型不匹配异常
+type VarTypeNotMatch struct {
+	varName    string
+	valueType  string
+	expectType string
+	reason     string
+}
+
+// Error 返回错误字符串
+func (i *VarTypeNotMatch) Error() string {
+	return fmt.Sprintf("value type not match of var %s, type: %s, expect type: %s, %s",
+		i.varName, i.valueType, i.expectType, i.reason)
+}
+
+// NewVarTypeNotMatchError 创建变量值类型不匹配异常
+// varName 变量名
+// valueType 设置的值的类型
+// expectType 变量的类型
+// reason 不匹配的原因
+func NewVarTypeNotMatchError(varName string, valueType, expectType string, reason string) error {
+	return &VarTypeNotMatch{varName: varName, valueType: valueType, expectType: expectType, reason: reason}
+}
@@ -0,0 +1,381 @@
+// Package mocker 定义了 mock 的外层用户使用 API 定义,
+// 包括函数、方法、接口、未导出函数(或方法的)的 Mocker 的实现。
+// 当前文件实现了外部命令的 mock: 通过 mock (*exec.Cmd).Run/Start/Wait/Output/CombinedOutput,
+// 按照命令名和参数匹配预设的输出、退出码和耗时, 并记录每一次执行的命令行和环境变量, 不会启动真实的进程。
+package mocker
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/tencent/goom/arg"
+	"github.com/tencent/goom/internal/logger"
+)
+
+// execCacheKey 外部命令 mock 在 Builder 中的缓存 key
+const execCacheKey = "exec"
+
+// stringType 命令名和参数的类型
+var stringType = reflect.TypeOf("")
+
+// ExecCall 一次外部命令的执行记录
+type ExecCall struct {
+	// Path 命令的路径
+	Path string
+	// Args 命令行, 包括命令名
+	Args []string
+	// Env 环境变量, 为 nil 时继承当前进程的环境变量
+	Env []string
+	// Dir 工作目录
+	Dir string
+}
+
+// String 命令行
+func (c ExecCall) String() string {
+	return strings.Join(c.Args, " ")
+}
+
+// ExecRule 外部命令的匹配条件和模拟的执行结果
+type ExecRule struct {
+	name     arg.Expr
+	args     []arg.Expr
+	stdout   []byte
+	stderr   []byte
+	exitCode int
+	delay    time.Duration
+}
+
+// Stdout 指定标准输出
+func (r *ExecRule) Stdout(stdout string) *ExecRule {
+	r.stdout = []byte(stdout)
+	return r
+}
+
+// Stderr 指定标准错误输出
+func (r *ExecRule) Stderr(stderr string) *ExecRule {
+	r.stderr = []byte(stderr)
+	return r
+}
+
+// ExitCode 指定退出码, 不为 0 时 Run、Wait 等返回 *exec.ExitError
+func (r *ExecRule) ExitCode(code int) *ExecRule {
+	r.exitCode = code
+	return r
+}
+
+// Delay 指定命令执行的耗时, Start 立即返回, Wait 在耗时之后返回
+func (r *ExecRule) Delay(delay time.Duration) *ExecRule {
+	r.delay = delay
+	return r
+}
+
+// match 命令名匹配 name 或者其文件名, 参数按照位置依次匹配; 实际参数多于匹配条件时, 剩余的参数以空格拼接后和最后一个条件匹配
+func (r *ExecRule) match(args []string) bool {
+	if len(args) == 0 || !(evalString(r.name, args[0]) || evalString(r.name, filepath.Base(args[0]))) {
+		return false
+	}
+	args = args[1:]
+	if len(r.args) == 0 {
+		return true
+	}
+	if len(args) < len(r.args) {
+		return false
+	}
+	last := len(r.args) - 1
+	for i := 0; i < last; i++ {
+		if !evalString(r.args[i], args[i]) {
+			return false
+		}
+	}
+	return evalString(r.args[last], strings.Join(args[last:], " "))
+}
+
+// evalString 执行字符串参数的匹配表达式
+func evalString(expr arg.Expr, s string) bool {
+	ok, err := expr.Eval([]reflect.Value{reflect.ValueOf(s)}, false)
+	return err == nil && ok
+}
+
+// execRun 模拟执行中的命令
+type execRun struct {
+	done   chan struct{}
+	state  *os.ProcessState
+	err    error
+	waited bool
+}
+
+// ExecMocker 外部命令 mock
+type ExecMocker struct {
+	lock  sync.Mutex
+	rules []*ExecRule
+	calls []ExecCall
+	// runs 已经 Start 的命令
+	runs     map[*exec.Cmd]*execRun
+	mockers  []Mocker
+	canceled bool
+}
+
+// Exec 创建外部命令 mock, 同一个 builder 多次调用返回同一个 mock
+// mock 之后所有通过 exec.Cmd 执行的命令都只能匹配 When 指定的规则, 没有匹配的规则时返回 *exec.Error
+func Exec(b *Builder) *ExecMocker {
+	if mocker, ok := b.mockers[execCacheKey]; ok && !mocker.Canceled() {
+		return mocker.(*ExecMocker)
+	}
+
+	m := &ExecMocker{runs: make(map[*exec.Cmd]*execRun)}
+	cmd := b.Struct(&exec.Cmd{})
+	run := cmd.Method("Run")
+	run.Apply(m.run)
+	start := cmd.Method("Start")
+	start.Apply(m.start)
+	wait := cmd.Method("Wait")
+	wait.Apply(m.wait)
+	output := cmd.Method("Output")
+	output.Apply(m.output)
+	combinedOutput := cmd.Method("CombinedOutput")
+	combinedOutput.Apply(m.combinedOutput)
+	m.mockers = []Mocker{run, start, wait, output, combinedOutput}
+
+	b.cache(execCacheKey, m)
+	logger.Consolefc(logger.DebugLevel, "mocker [%s] apply.", logger.Caller(5), m.String())
+	return m
+}
+
+// When 添加匹配规则, 按照添加的顺序匹配, 默认的执行结果为: 没有输出, 退出码为 0
+// name 命令名, 可以是字符串或者参数表达式, 比如: arg.HasPrefix("git")
+// args 命令参数, 可以是字符串或者参数表达式, 比如: When("git", arg.HasPrefix("rev-parse")) 匹配 git rev-parse HEAD
+func (m *ExecMocker) When(name interface{}, args ...interface{}) *ExecRule {
+	exprs, err := arg.ToExpr(append([]interface{}{name}, args...), stringTypes(len(args)+1), false)
+	if err != nil {
+		panic(err)
+	}
+	rule := &ExecRule{name: exprs[0], args: exprs[1:]}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.rules = append(m.rules, rule)
+	return rule
+}
+
+// Calls 获取执行过的命令, 包括没有匹配规则的命令
+func (m *ExecMocker) Calls() []ExecCall {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return append([]ExecCall(nil), m.calls...)
+}
+
+// Apply 不支持, 请使用 When 添加规则
+func (m *ExecMocker) Apply(interface{}) {
+	panic("ExecMocker does not support Apply, use When(name, args...) instead.")
+}
+
+// Cancel 取消 mock, 恢复执行真实的命令
+func (m *ExecMocker) Cancel() {
+	for _, mocker := range m.mockers {
+		mocker.Cancel()
+	}
+	m.canceled = true
+}
+
+// Canceled 是否取消了 mock
+func (m *ExecMocker) Canceled() bool {
+	return m.canceled
+}
+
+// String mock 的名称或描述, 方便调试和问题排查
+func (m *ExecMocker) String() string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return fmt.Sprintf("exec[rules: %d, calls: %d]", len(m.rules), len(m.calls))
+}
+
+func (m *ExecMocker) run(c *exec.Cmd) error {
+	if err := m.start(c); err != nil {
+		return err
+	}
+	return m.wait(c)
+}
+
+// start 匹配规则并在新的协程中模拟执行: 等待耗时之后写出标准输出和标准错误输出,
+// 然后关闭 StdinPipe、StdoutPipe、StderrPipe 创建的子进程一端的管道, 读取管道的协程可以读到 EOF
+func (m *ExecMocker) start(c *exec.Cmd) error {
+	args := c.Args
+	if len(args) == 0 {
+		args = []string{c.Path}
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, ok := m.runs[c]; ok || c.Process != nil {
+		return errors.New("exec: already started")
+	}
+	m.calls = append(m.calls, ExecCall{
+		Path: c.Path,
+		Args: append([]string(nil), args...),
+		Env:  append([]string(nil), c.Env...),
+		Dir:  c.Dir,
+	})
+	var rule *ExecRule
+	for _, r := range m.rules {
+		if r.match(args) {
+			rule = r
+			break
+		}
+	}
+	if rule == nil {
+		return &exec.Error{Name: strings.Join(args, " "),
+			Err: errors.New("goom: no fake command matched, add it with mocker.Exec(builder).When")}
+	}
+
+	r := &execRun{done: make(chan struct{})}
+	m.runs[c] = r
+	c.Process = newReleasedProcess()
+	go func() {
+		defer close(r.done)
+		if rule.delay > 0 {
+			time.Sleep(rule.delay)
+		}
+		r.err = writeOutput(c.Stdout, rule.stdout)
+		if err := writeOutput(c.Stderr, rule.stderr); r.err == nil {
+			r.err = err
+		}
+		closeCmdFiles(c, "childIOFiles", "closeAfterStart")
+		r.state = newProcessState(rule.exitCode)
+	}()
+	return nil
+}
+
+// wait 等待模拟执行完成, 关闭 StdoutPipe、StderrPipe 返回的管道, 退出码不为 0 时返回 *exec.ExitError
+func (m *ExecMocker) wait(c *exec.Cmd) error {
+	m.lock.Lock()
+	r, ok := m.runs[c]
+	if !ok {
+		m.lock.Unlock()
+		if c.ProcessState != nil {
+			return errors.New("exec: Wait was already called")
+		}
+		return errors.New("exec: not started")
+	}
+	if r.waited {
+		m.lock.Unlock()
+		return errors.New("exec: Wait was already called")
+	}
+	r.waited = true
+	m.lock.Unlock()
+
+	<-r.done
+	closeCmdFiles(c, "parentIOPipes", "closeAfterWait")
+	c.ProcessState = r.state
+	m.lock.Lock()
+	delete(m.runs, c)
+	m.lock.Unlock()
+	if !r.state.Success() {
+		return &exec.ExitError{ProcessState: r.state}
+	}
+	return r.err
+}
+
+func (m *ExecMocker) output(c *exec.Cmd) ([]byte, error) {
+	if c.Stdout != nil {
+		return nil, errors.New("exec: Stdout already set")
+	}
+	var stdout, stderr bytes.Buffer
+	c.Stdout = &stdout
+	captureErr := c.Stderr == nil
+	if captureErr {
+		c.Stderr = &stderr
+	}
+	err := m.run(c)
+	var exitErr *exec.ExitError
+	if captureErr && errors.As(err, &exitErr) {
+		exitErr.Stderr = stderr.Bytes()
+	}
+	return stdout.Bytes(), err
+}
+
+func (m *ExecMocker) combinedOutput(c *exec.Cmd) ([]byte, error) {
+	if c.Stdout != nil {
+		return nil, errors.New("exec: Stdout already set")
+	}
+	if c.Stderr != nil {
+		return nil, errors.New("exec: Stderr already set")
+	}
+	var output bytes.Buffer
+	c.Stdout = &output
+	c.Stderr = &output
+	err := m.run(c)
+	return output.Bytes(), err
+}
+
+// writeOutput 将模拟的输出写入命令的输出, 命令没有设置输出时丢弃
+func writeOutput(w io.Writer, data []byte) error {
+	if w == nil || len(data) == 0 {
+		return nil
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// stringTypes n 个字符串参数的类型
+func stringTypes(n int) []reflect.Type {
+	types := make([]reflect.Type, n)
+	for i := range types {
+		types[i] = stringType
+	}
+	return types
+}
+
+// closeCmdFiles 关闭 exec.Cmd 未导出字段中保存的管道并清空字段, 不同的 go 版本字段名不同, 不存在的字段忽略
+func closeCmdFiles(c *exec.Cmd, fields ...string) {
+	cmd := reflect.ValueOf(c).Elem()
+	for _, name := range fields {
+		field := cmd.FieldByName(name)
+		if !field.IsValid() || field.Kind() != reflect.Slice {
+			continue
+		}
+		field = reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem()
+		for i := 0; i < field.Len(); i++ {
+			if closer, ok := field.Index(i).Interface().(io.Closer); ok {
+				_ = closer.Close()
+			}
+		}
+		field.Set(reflect.Zero(field.Type()))
+	}
+}
+
+// newReleasedProcess 构造已经释放的进程作为 Cmd.Process 的占位,
+// Kill、Signal、Wait 都返回错误, 不会影响真实的进程
+func newReleasedProcess() *os.Process {
+	p := &os.Process{}
+	_ = p.Release()
+	return p
+}
+
+// newProcessState 构造指定退出码的进程状态, os.ProcessState 没有公开的构造方法, 这里直接设置未导出的 status 字段
+func newProcessState(code int) *os.ProcessState {
+	state := &os.ProcessState{}
+	status := reflect.ValueOf(state).Elem().FieldByName("status")
+	if !status.IsValid() {
+		return state
+	}
+	status = reflect.NewAt(status.Type(), unsafe.Pointer(status.UnsafeAddr())).Elem()
+	switch status.Kind() {
+	case reflect.Uint32:
+		// unix: 正常退出时低 8 位为 0, 退出码位于 8~15 位
+		status.SetUint(uint64(code&0xff) << 8)
+	case reflect.Struct:
+		// windows: syscall.WaitStatus{ExitCode uint32}
+		if exitCode := status.FieldByName("ExitCode"); exitCode.IsValid() && exitCode.CanSet() {
+			exitCode.SetUint(uint64(code))
+		}
+	}
+	return state
+}
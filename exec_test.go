@@ -0,0 +1,104 @@
+package mocker_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/tencent/goom"
+	"github.com/tencent/goom/arg"
+)
+
+// TestUnitExecTestSuite 测试入口
+func TestUnitExecTestSuite(t *testing.T) {
+	suite.Run(t, new(execTestSuite))
+}
+
+type execTestSuite struct {
+	suite.Suite
+}
+
+// TestUnitExec 测试模拟外部命令的输出和退出码
+func (s *execTestSuite) TestUnitExec() {
+	mock := mocker.Create()
+	defer mock.Reset()
+	fake := mocker.Exec(mock)
+	fake.When("git", arg.HasPrefix("rev-parse")).Stdout("abc\n")
+	fake.When("git", "push").Stderr("rejected").ExitCode(2)
+	fake.When("sleep").Delay(50 * time.Millisecond)
+
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Env = []string{"GIT_DIR=/tmp/repo"}
+	cmd.Dir = "/tmp"
+	out, err := cmd.Output()
+	s.NoError(err, "output check")
+	s.Equal("abc\n", string(out), "output check")
+	s.Equal(0, cmd.ProcessState.ExitCode(), "exit code check")
+
+	out, err = exec.Command("git", "push").CombinedOutput()
+	s.Equal("rejected", string(out), "combined output check")
+	var exitErr *exec.ExitError
+	s.True(errors.As(err, &exitErr), "exit error check")
+	s.Equal(2, exitErr.ExitCode(), "exit code check")
+	s.Equal("exit status 2", err.Error(), "exit error check")
+
+	_, err = exec.Command("git", "push").Output()
+	s.True(errors.As(err, &exitErr), "output exit error check")
+	s.Equal("rejected", string(exitErr.Stderr), "output stderr check")
+
+	var stdout bytes.Buffer
+	cmd = exec.Command("sleep", "10")
+	cmd.Stdout = &stdout
+	begin := time.Now()
+	s.NoError(cmd.Start(), "start check")
+	s.NoError(cmd.Wait(), "wait check")
+	s.GreaterOrEqual(int64(time.Since(begin)), int64(50*time.Millisecond), "delay check")
+	s.Error(cmd.Wait(), "wait twice check")
+
+	err = exec.Command("rm", "-rf", "/").Run()
+	var execErr *exec.Error
+	s.True(errors.As(err, &execErr), "no match check")
+
+	calls := fake.Calls()
+	s.Len(calls, 5, "calls check")
+	s.Equal("git rev-parse HEAD", calls[0].String(), "calls check")
+	s.Equal([]string{"GIT_DIR=/tmp/repo"}, calls[0].Env, "calls env check")
+	s.Equal("/tmp", calls[0].Dir, "calls dir check")
+	s.Equal("rm -rf /", calls[4].String(), "calls check")
+}
+
+// TestUnitExecPipe 测试通过 StdoutPipe 读取模拟的输出, 以及 Start 之后的 Process 占位
+func (s *execTestSuite) TestUnitExecPipe() {
+	mock := mocker.Create()
+	defer mock.Reset()
+	fake := mocker.Exec(mock)
+	fake.When("git", "log").Stdout("commit\n").Delay(10 * time.Millisecond)
+
+	cmd := exec.Command("git", "log")
+	pipe, err := cmd.StdoutPipe()
+	s.Require().NoError(err, "pipe check")
+	s.Require().NoError(cmd.Start(), "start check")
+	s.NotPanics(func() {
+		_ = cmd.Process.Pid
+	}, "process check")
+	s.Error(cmd.Process.Kill(), "released process check")
+	s.Error(cmd.Start(), "start twice check")
+
+	done := make(chan []byte, 1)
+	go func() {
+		data, _ := ioutil.ReadAll(pipe)
+		done <- data
+	}()
+	select {
+	case data := <-done:
+		s.Equal("commit\n", string(data), "pipe output check")
+	case <-time.After(time.Second):
+		s.Fail("pipe not closed")
+	}
+	s.NoError(cmd.Wait(), "wait check")
+	s.EqualError(cmd.Wait(), "exec: Wait was already called", "wait twice check")
+}
@@ -0,0 +1,43 @@
+// Package mocker 定义了 mock 的外层用户使用 API 定义,
+// 包括函数、方法、接口、未导出函数(或方法的)的 Mocker 的实现。
+// 当前文件实现了进程退出的拦截: 将 os.Exit 替换为可以 recover 的 panic, 以便测试调用了 os.Exit、log.Fatal 的代码路径。
+package mocker
+
+import (
+	"fmt"
+	"os"
+)
+
+// ExitSignal 拦截到 os.Exit 时用于展开调用协程的 panic 值
+type ExitSignal struct {
+	// Code 退出码
+	Code int
+}
+
+// Error 返回错误字符串
+func (e *ExitSignal) Error() string {
+	return fmt.Sprintf("os.Exit(%d) intercepted, it must be called in the goroutine of CatchExit", e.Code)
+}
+
+// CatchExit 执行 fn, 并拦截其中对 os.Exit 的调用, 返回退出码以及是否调用了 os.Exit
+// log.Fatal*、(*log.Logger).Fatal* 以及 klog.Fatalf 等最终调用 os.Exit 的函数同样会被拦截;
+// 拦截到 os.Exit 时会以 panic 的方式展开 fn 所在的协程(fn 中的 defer 会被执行), 执行完成后恢复 os.Exit
+// 注意: os.Exit 必须在 fn 所在的协程中调用, 在其它协程中调用时 panic 无法被 recover
+func CatchExit(b *Builder, fn func()) (code int, exited bool) {
+	mocker := b.Func(os.Exit)
+	mocker.Apply(func(code int) {
+		panic(&ExitSignal{Code: code})
+	})
+	defer mocker.Cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			signal, ok := r.(*ExitSignal)
+			if !ok {
+				panic(r)
+			}
+			code, exited = signal.Code, true
+		}
+	}()
+	fn()
+	return 0, false
+}
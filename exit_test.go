@@ -0,0 +1,58 @@
+package mocker_test
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	mocker "github.com/tencent/goom"
+)
+
+// TestUnitExitTestSuite 测试入口
+func TestUnitExitTestSuite(t *testing.T) {
+	suite.Run(t, new(exitTestSuite))
+}
+
+type exitTestSuite struct {
+	suite.Suite
+}
+
+// TestUnitCatchExit 测试拦截 os.Exit 和 log.Fatal
+func (s *exitTestSuite) TestUnitCatchExit() {
+	s.Run("os.Exit", func() {
+		mock := mocker.Create()
+		deferred := false
+		code, exited := mocker.CatchExit(mock, func() {
+			defer func() { deferred = true }()
+			os.Exit(3)
+		})
+		s.True(exited, "exited check")
+		s.Equal(3, code, "exit code check")
+		s.True(deferred, "defer check")
+	})
+	s.Run("log.Fatal", func() {
+		mock := mocker.Create()
+		logger := log.New(ioutil.Discard, "", 0)
+		code, exited := mocker.CatchExit(mock, func() {
+			logger.Fatalf("fatal: %s", "error")
+		})
+		s.True(exited, "exited check")
+		s.Equal(1, code, "exit code check")
+	})
+	s.Run("no exit", func() {
+		mock := mocker.Create()
+		code, exited := mocker.CatchExit(mock, func() {})
+		s.False(exited, "exited check")
+		s.Equal(0, code, "exit code check")
+	})
+	s.Run("panic", func() {
+		mock := mocker.Create()
+		s.PanicsWithValue("other panic", func() {
+			mocker.CatchExit(mock, func() {
+				panic("other panic")
+			})
+		}, "other panic check")
+	})
+}
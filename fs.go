@@ -0,0 +1,374 @@
+// Package mocker 定义了 mock 的外层用户使用 API 定义,
+// 包括函数、方法、接口、未导出函数(或方法的)的 Mocker 的实现。
+// 当前文件实现了文件系统的重定向: 通过 mock os.Open、os.ReadFile、os.Stat、os.ReadDir、os.WriteFile,
+// 将指定路径前缀下的文件操作重定向到内存中的 fstest.MapFS, 其它路径调用原函数。
+package mocker
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing/fstest"
+	"time"
+
+	"github.com/tencent/goom/internal/logger"
+)
+
+// fsCacheKey 文件系统 mock 在 Builder 中的缓存 key
+const fsCacheKey = "fs"
+
+// fsMount 挂载到路径前缀下的内存文件系统
+type fsMount struct {
+	prefixes []string
+	fsys     fstest.MapFS
+}
+
+// FSMocker 文件系统 mock
+type FSMocker struct {
+	lock   sync.RWMutex
+	mounts []*fsMount
+	// written 通过 os.WriteFile 写入的内容, key 为清理后的路径
+	written map[string][]byte
+	// tempDir os.Open 打开内存文件时, 将内容写出到的临时目录
+	tempDir  string
+	mockers  []Mocker
+	canceled bool
+
+	// 调用原函数的跳板函数
+	open      func(name string) (*os.File, error)
+	readFile  func(name string) ([]byte, error)
+	stat      func(name string) (os.FileInfo, error)
+	readDir   func(name string) ([]os.DirEntry, error)
+	writeFile func(name string, data []byte, perm os.FileMode) error
+}
+
+// FS 将 prefixes 路径前缀下的文件操作重定向到内存文件系统 fsys, 比如: 将 /etc/app 重定向后,
+// os.ReadFile("/etc/app/conf.yaml") 读取的是 fsys["etc/app/conf.yaml"]; 不指定 prefixes 时使用 fsys 的顶层目录
+// os.WriteFile 写入的内容保存到 fsys 中, 可以通过 Written 获取; 同一个 builder 多次调用时挂载多个内存文件系统
+// 注意: os.Open 打开内存文件时, 返回的是内容相同的临时文件, 其 Name() 为临时文件的路径
+func FS(b *Builder, fsys fstest.MapFS, prefixes ...string) *FSMocker {
+	if len(prefixes) == 0 {
+		prefixes = topDirs(fsys)
+	}
+	cleaned := make([]string, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		cleaned = append(cleaned, cleanPath(prefix))
+	}
+	prefixes = cleaned
+
+	if mocker, ok := b.mockers[fsCacheKey]; ok && !mocker.Canceled() {
+		m := mocker.(*FSMocker)
+		m.lock.Lock()
+		m.mounts = append(m.mounts, &fsMount{prefixes: prefixes, fsys: fsys})
+		m.lock.Unlock()
+		return m
+	}
+
+	m := &FSMocker{
+		mounts:    []*fsMount{{prefixes: prefixes, fsys: fsys}},
+		written:   make(map[string][]byte),
+		open:      openTrampoline,
+		readFile:  readFileTrampoline,
+		stat:      statTrampoline,
+		readDir:   readDirTrampoline,
+		writeFile: writeFileTrampoline,
+	}
+	open := b.Func(os.Open)
+	open.Origin(&m.open).Apply(m.doOpen)
+	readFile := b.Func(os.ReadFile)
+	readFile.Origin(&m.readFile).Apply(m.doReadFile)
+	stat := b.Func(os.Stat)
+	stat.Origin(&m.stat).Apply(m.doStat)
+	readDir := b.Func(os.ReadDir)
+	readDir.Origin(&m.readDir).Apply(m.doReadDir)
+	writeFile := b.Func(os.WriteFile)
+	writeFile.Origin(&m.writeFile).Apply(m.doWriteFile)
+	m.mockers = []Mocker{open, readFile, stat, readDir, writeFile}
+
+	b.cache(fsCacheKey, m)
+	logger.Consolefc(logger.DebugLevel, "mocker [%s] apply.", logger.Caller(5), m.String())
+	return m
+}
+
+// Written 获取通过 os.WriteFile 写入到内存文件系统的内容
+func (m *FSMocker) Written(name string) ([]byte, bool) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	data, ok := m.written[cleanPath(name)]
+	return data, ok
+}
+
+// Apply 不支持, 请在 FS 中指定内存文件系统
+func (m *FSMocker) Apply(interface{}) {
+	panic("FSMocker does not support Apply, use mocker.FS(builder, fsys, prefixes...) instead.")
+}
+
+// Cancel 取消 mock, 删除 os.Open 产生的临时文件
+func (m *FSMocker) Cancel() {
+	for _, mocker := range m.mockers {
+		mocker.Cancel()
+	}
+	m.lock.Lock()
+	tempDir := m.tempDir
+	m.tempDir = ""
+	m.lock.Unlock()
+	if tempDir != "" {
+		_ = os.RemoveAll(tempDir)
+	}
+	m.canceled = true
+}
+
+// Canceled 是否取消了 mock
+func (m *FSMocker) Canceled() bool {
+	return m.canceled
+}
+
+// String mock 的名称或描述, 方便调试和问题排查
+func (m *FSMocker) String() string {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	prefixes := make([]string, 0, len(m.mounts))
+	for _, mount := range m.mounts {
+		prefixes = append(prefixes, mount.prefixes...)
+	}
+	return fmt.Sprintf("fs[%s]", strings.Join(prefixes, ","))
+}
+
+// resolve 查找路径所在的内存文件系统, 返回其中的相对路径; 未重定向的路径返回 nil
+func (m *FSMocker) resolve(name string) (fstest.MapFS, string) {
+	name = cleanPath(name)
+	for _, mount := range m.mounts {
+		for _, prefix := range mount.prefixes {
+			if name == prefix || strings.HasPrefix(name, strings.TrimSuffix(prefix, "/")+"/") {
+				rel := strings.TrimPrefix(name, "/")
+				if rel == "" {
+					rel = "."
+				}
+				return mount.fsys, rel
+			}
+		}
+	}
+	return nil, ""
+}
+
+func (m *FSMocker) doReadFile(name string) ([]byte, error) {
+	m.lock.RLock()
+	fsys, rel := m.resolve(name)
+	if fsys == nil {
+		m.lock.RUnlock()
+		return m.readFile(name)
+	}
+	defer m.lock.RUnlock()
+	data, err := fs.ReadFile(fsys, rel)
+	return data, pathError(err, name)
+}
+
+func (m *FSMocker) doStat(name string) (os.FileInfo, error) {
+	m.lock.RLock()
+	fsys, rel := m.resolve(name)
+	if fsys == nil {
+		m.lock.RUnlock()
+		return m.stat(name)
+	}
+	defer m.lock.RUnlock()
+	info, err := fs.Stat(fsys, rel)
+	return info, pathError(err, name)
+}
+
+func (m *FSMocker) doReadDir(name string) ([]os.DirEntry, error) {
+	m.lock.RLock()
+	fsys, rel := m.resolve(name)
+	if fsys == nil {
+		m.lock.RUnlock()
+		return m.readDir(name)
+	}
+	defer m.lock.RUnlock()
+	entries, err := fs.ReadDir(fsys, rel)
+	return entries, pathError(err, name)
+}
+
+func (m *FSMocker) doWriteFile(name string, data []byte, perm os.FileMode) error {
+	m.lock.Lock()
+	fsys, rel := m.resolve(name)
+	if fsys == nil {
+		m.lock.Unlock()
+		return m.writeFile(name, data, perm)
+	}
+	defer m.lock.Unlock()
+	if info, err := fs.Stat(fsys, rel); err == nil && info.IsDir() {
+		return &fs.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+	}
+	content := append([]byte(nil), data...)
+	if file, ok := fsys[rel]; ok {
+		// 和真实的文件一样, 已存在的文件保留原来的权限
+		perm = file.Mode
+	}
+	fsys[rel] = &fstest.MapFile{Data: content, Mode: perm, ModTime: time.Now()}
+	m.written[cleanPath(name)] = content
+	return nil
+}
+
+// doOpen 打开内存文件时, 将文件(或者目录下的所有文件)写出到临时目录中再打开
+func (m *FSMocker) doOpen(name string) (*os.File, error) {
+	m.lock.RLock()
+	fsys, rel := m.resolve(name)
+	if fsys == nil {
+		m.lock.RUnlock()
+		return m.open(name)
+	}
+	files, err := snapshot(fsys, rel)
+	m.lock.RUnlock()
+	if err != nil {
+		return nil, pathError(err, name)
+	}
+
+	dir, err := m.openDir()
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if err = file.writeTo(dir); err != nil {
+			return nil, err
+		}
+	}
+	return m.open(filepath.Join(dir, filepath.FromSlash(rel)))
+}
+
+// openDir 为每次 os.Open 创建一个独立的临时目录, 避免覆盖之前打开的文件
+// 创建目录时不能持有锁: os.MkdirTemp 可能调用被 mock 的 os.Stat, 而 os.Stat 的 mock 需要获取读锁
+func (m *FSMocker) openDir() (string, error) {
+	m.lock.RLock()
+	tempDir := m.tempDir
+	m.lock.RUnlock()
+	if tempDir == "" {
+		dir, err := os.MkdirTemp("", "goom-fs-")
+		if err != nil {
+			return "", err
+		}
+		m.lock.Lock()
+		if m.tempDir == "" {
+			m.tempDir = dir
+		}
+		tempDir = m.tempDir
+		m.lock.Unlock()
+		if tempDir != dir {
+			// 其它协程已经创建了临时目录
+			_ = os.RemoveAll(dir)
+		}
+	}
+	return os.MkdirTemp(tempDir, "open-")
+}
+
+// snapshotFile 内存文件系统中的文件或目录的快照
+type snapshotFile struct {
+	rel  string
+	data []byte
+	mode fs.FileMode
+}
+
+// writeTo 将快照写出到 dir 目录下
+func (f *snapshotFile) writeTo(dir string) error {
+	target := filepath.Join(dir, filepath.FromSlash(f.rel))
+	if f.mode.IsDir() {
+		return os.MkdirAll(target, 0755)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.mode.Perm()|0400)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(f.data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// snapshot 获取内存文件系统中 rel 文件或者 rel 目录下所有文件的快照, 调用方需要持有锁
+func snapshot(fsys fstest.MapFS, rel string) ([]*snapshotFile, error) {
+	var files []*snapshotFile
+	err := fs.WalkDir(fsys, rel, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			files = append(files, &snapshotFile{rel: p, mode: fs.ModeDir})
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		mode := fs.FileMode(0644)
+		if file, ok := fsys[p]; ok && file.Mode.Perm() != 0 {
+			mode = file.Mode.Perm()
+		}
+		files = append(files, &snapshotFile{rel: p, data: data, mode: mode})
+		return nil
+	})
+	return files, err
+}
+
+// topDirs 内存文件系统的顶层目录, 作为默认的重定向路径前缀
+func topDirs(fsys fstest.MapFS) []string {
+	seen := make(map[string]bool)
+	for name := range fsys {
+		top := "/" + strings.SplitN(path.Clean(name), "/", 2)[0]
+		seen[top] = true
+	}
+	dirs := make([]string, 0, len(seen))
+	for dir := range seen {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// cleanPath 统一路径的格式: 使用/分隔, 去除多余的分隔符和.、..
+func cleanPath(name string) string {
+	return path.Clean(filepath.ToSlash(name))
+}
+
+// pathError 将内存文件系统返回错误中的相对路径替换为调用方传入的路径
+func pathError(err error, name string) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return &fs.PathError{Op: pathErr.Op, Path: name, Err: pathErr.Err}
+	}
+	return err
+}
+
+// 以下为调用原函数的跳板函数占位, mock 之后函数体会被替换, 实际不会执行该函数体, 但是必须编写
+
+func openTrampoline(name string) (*os.File, error) {
+	fmt.Println("only for placeholder, will not call", name)
+	return nil, nil
+}
+
+func readFileTrampoline(name string) ([]byte, error) {
+	fmt.Println("only for placeholder, will not call", name)
+	return nil, nil
+}
+
+func statTrampoline(name string) (os.FileInfo, error) {
+	fmt.Println("only for placeholder, will not call", name)
+	return nil, nil
+}
+
+func readDirTrampoline(name string) ([]os.DirEntry, error) {
+	fmt.Println("only for placeholder, will not call", name)
+	return nil, nil
+}
+
+func writeFileTrampoline(name string, data []byte, perm os.FileMode) error {
+	fmt.Println("only for placeholder, will not call", name, len(data), perm)
+	return nil
+}
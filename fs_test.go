@@ -0,0 +1,117 @@
+package mocker_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/tencent/goom"
+)
+
+// TestUnitFSTestSuite 测试入口
+func TestUnitFSTestSuite(t *testing.T) {
+	suite.Run(t, new(fsTestSuite))
+}
+
+type fsTestSuite struct {
+	suite.Suite
+}
+
+// TestUnitFS 测试将指定路径前缀重定向到内存文件系统
+func (s *fsTestSuite) TestUnitFS() {
+	realDir := s.T().TempDir()
+	realFile := filepath.Join(realDir, "real.txt")
+	s.Require().NoError(ioutil.WriteFile(realFile, []byte("real"), 0644))
+
+	fsys := fstest.MapFS{
+		"etc/app/conf.yaml": &fstest.MapFile{Data: []byte("port: 80")},
+		"etc/app/certs/ca":  &fstest.MapFile{Data: []byte("ca")},
+	}
+	mock := mocker.Create()
+	fsMocker := mocker.FS(mock, fsys, "/etc/app")
+
+	data, err := os.ReadFile("/etc/app/conf.yaml")
+	s.NoError(err, "ReadFile check")
+	s.Equal("port: 80", string(data), "ReadFile check")
+
+	info, err := os.Stat("/etc/app/conf.yaml")
+	s.NoError(err, "Stat check")
+	s.Equal(int64(8), info.Size(), "Stat check")
+	_, err = os.Stat("/etc/app/none.yaml")
+	s.True(os.IsNotExist(err), "Stat not exist check")
+	s.Contains(err.Error(), "/etc/app/none.yaml", "error path check")
+
+	entries, err := os.ReadDir("/etc/app")
+	s.NoError(err, "ReadDir check")
+	s.Len(entries, 2, "ReadDir check")
+	s.Equal("certs", entries[0].Name(), "ReadDir check")
+
+	file, err := os.Open("/etc/app/certs/ca")
+	s.NoError(err, "Open check")
+	data, err = ioutil.ReadAll(file)
+	s.NoError(err, "Open read check")
+	s.Equal("ca", string(data), "Open read check")
+	s.NoError(file.Close(), "Open close check")
+
+	s.NoError(os.WriteFile("/etc/app/out.yaml", []byte("written"), 0600), "WriteFile check")
+	written, ok := fsMocker.Written("/etc/app/out.yaml")
+	s.True(ok, "Written check")
+	s.Equal("written", string(written), "Written check")
+	s.Equal("written", string(fsys["etc/app/out.yaml"].Data), "WriteFile fsys check")
+	data, err = os.ReadFile("/etc/app/out.yaml")
+	s.NoError(err, "read written check")
+	s.Equal("written", string(data), "read written check")
+
+	// 其它路径调用原函数
+	data, err = os.ReadFile(realFile)
+	s.NoError(err, "pass through check")
+	s.Equal("real", string(data), "pass through check")
+	_, err = os.Stat(realFile)
+	s.NoError(err, "pass through stat check")
+	s.NoError(os.WriteFile(filepath.Join(realDir, "out.txt"), []byte("out"), 0644), "pass through write check")
+	_, ok = fsMocker.Written(filepath.Join(realDir, "out.txt"))
+	s.False(ok, "pass through write check")
+	file, err = os.Open(realFile)
+	s.NoError(err, "pass through open check")
+	s.Equal(realFile, file.Name(), "pass through open check")
+	s.NoError(file.Close(), "pass through open check")
+
+	mock.Reset()
+	_, err = os.ReadFile("/etc/app/conf.yaml")
+	s.True(os.IsNotExist(err), "reset check")
+}
+
+// TestUnitFSDefaultPrefix 测试默认使用内存文件系统的顶层目录作为路径前缀
+func (s *fsTestSuite) TestUnitFSDefaultPrefix() {
+	mock := mocker.Create()
+	defer mock.Reset()
+	mocker.FS(mock, fstest.MapFS{"goom-fs/a.txt": &fstest.MapFile{Data: []byte("a")}})
+
+	data, err := os.ReadFile("/goom-fs/a.txt")
+	s.NoError(err, "ReadFile check")
+	s.Equal("a", string(data), "ReadFile check")
+}
+
+// TestUnitFSOpenTempDirError 测试创建临时目录失败时 os.Open 返回错误, 不会因为 mock 的 os.Stat 死锁
+func (s *fsTestSuite) TestUnitFSOpenTempDirError() {
+	s.T().Setenv("TMPDIR", filepath.Join(s.T().TempDir(), "none"))
+	mock := mocker.Create()
+	defer mock.Reset()
+	mocker.FS(mock, fstest.MapFS{"etc/app/a.txt": &fstest.MapFile{Data: []byte("a")}}, "/etc/app")
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := os.Open("/etc/app/a.txt")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		s.Error(err, "Open temp dir error check")
+	case <-time.After(5 * time.Second):
+		s.Fail("Open deadlock")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"unsafe"
 
+	"github.com/tencent/goom/erro"
 	"github.com/tencent/goom/internal/iface"
 	"github.com/tencent/goom/internal/logger"
 )
@@ -77,9 +78,9 @@ func (m *DefaultInterfaceMocker) Method(name string) InterfaceMocker {
 // checkMethod 检查是否能找到函数
 func (m *DefaultInterfaceMocker) checkMethod(name string) {
 	sTyp := reflect.TypeOf(m.iFace).Elem()
-	_, ok := sTyp.MethodByName(name)
-	if !ok {
-		panic("method " + name + " not found on " + sTyp.String())
+	// 不能使用 MethodByName, 需要同时支持未导出的接口方法
+	if _, ok := iface.MethodIndex(sTyp, name); !ok {
+		panic(erro.NewFuncNotFoundErrorWithSuggestion(sTyp.String()+"."+name, iface.MethodNames(sTyp)))
 	}
 }
 
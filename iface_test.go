@@ -175,6 +175,49 @@ func (s *ifaceMockerTestSuite) TestUnitArgsNotMatch() {
 	})
 }
 
+// TestUnitUnexportedMethodApply 测试接口未导出方法 mock apply, 不影响其它方法的方法表位置
+func (s *ifaceMockerTestSuite) TestUnitUnexportedMethodApply() {
+	s.Run("success", func() {
+		mock := mocker.Create()
+		i := (I)(nil)
+
+		mock.Interface(&i).Method("call2").Apply(func(ctx *mocker.IContext, i int32) int32 {
+			return i * 2
+		})
+		mock.Interface(&i).Method("Call").Apply(func(ctx *mocker.IContext, i int) int {
+			return i * 3
+		})
+
+		t := NewTestTarget(i)
+		s.Equal(int32(4), t.Call2(2), "unexported method mock check")
+		s.Equal(6, t.Call(2), "exported method mock check")
+
+		mock.Reset()
+		s.Nil(i, "interface mock reset check")
+	})
+}
+
+// TestUnitMethodNotFound 测试接口 mock 方法名不存在的情况
+func (s *ifaceMockerTestSuite) TestUnitMethodNotFound() {
+	s.Run("success", func() {
+		var expectErr error
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					expectErr, _ = err.(error)
+				}
+			}()
+
+			mock := mocker.Create()
+			i := (I)(nil)
+			mock.Interface(&i).Method("call3")
+		}()
+
+		s.IsType(&erro.FuncNotFound{}, expectErr, "method not found check")
+		s.Contains(expectErr.Error(), "call2", "method not found suggestion check")
+	})
+}
+
 // I 接口测试
 type I interface {
 	Call(int) int
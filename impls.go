@@ -0,0 +1,254 @@
+// Package mocker 定义了 mock 的外层用户使用 API 定义,
+// 包括函数、方法、接口、未导出函数(或方法的)的 Mocker 的实现。
+// 当前文件实现了对接口的所有实现类的方法同时进行 mock 的能力。
+package mocker
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/tencent/goom/erro"
+	"github.com/tencent/goom/internal/logger"
+	"github.com/tencent/goom/internal/proxy"
+	"github.com/tencent/goom/internal/unexports2"
+)
+
+// ImplementationsMocker 接口的所有实现类的 Mock
+// 通过二进制中登记的类型查找接口的所有实现类, 并对每个实现类的方法分别进行 mock, 取消时一起取消
+type ImplementationsMocker struct {
+	pkgName string
+	iFace   reflect.Type
+	impls   []reflect.Type
+	methods map[string]*ImplMethodMocker
+}
+
+// NewImplementationsMocker 创建接口的所有实现类的 Mocker
+// pkgName 包路径
+// iFace 接口类型的指针, 比如 (*I)(nil)
+func NewImplementationsMocker(pkgName string, iFace interface{}) *ImplementationsMocker {
+	t := reflect.TypeOf(iFace)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Interface {
+		panic(erro.NewIllegalParamTypeError("iFace", fmt.Sprintf("%T", iFace), "*interface"))
+	}
+	return &ImplementationsMocker{
+		pkgName: pkgName,
+		iFace:   t.Elem(),
+		impls:   unexports2.AllImplementations(t.Elem()),
+		methods: make(map[string]*ImplMethodMocker, 8),
+	}
+}
+
+// String mock 的名称或描述, 方便调试和问题排查
+func (m *ImplementationsMocker) String() string {
+	return fmt.Sprintf("%s(%d implementations)", m.iFace.String(), len(m.impls))
+}
+
+// Implementations 返回查找到的接口实现类
+func (m *ImplementationsMocker) Implementations() []reflect.Type {
+	return m.impls
+}
+
+// Method 指定接口方法
+func (m *ImplementationsMocker) Method(name string) *ImplMethodMocker {
+	if name == "" {
+		panic("method is empty")
+	}
+	if mocker, ok := m.methods[name]; ok && !mocker.Canceled() {
+		return mocker
+	}
+	method, ok := m.iFace.MethodByName(name)
+	if !ok {
+		panic(erro.NewFuncNotFoundError(m.iFace.String() + "." + name))
+	}
+	if len(m.impls) == 0 {
+		panic(erro.NewIllegalStatusError("AllImplementations",
+			"no implementation of "+m.iFace.String()+" found in binary"))
+	}
+	mocker := &ImplMethodMocker{
+		baseMocker: newBaseMocker(m.pkgName),
+		iFace:      m.iFace,
+		method:     name,
+		impls:      m.impls,
+	}
+	mocker.funcDef = reflect.Zero(prependIn(m.iFace, method.Type)).Interface()
+	m.methods[name] = mocker
+	return mocker
+}
+
+// Apply 不支持整体 Apply, 请使用 Method(name).Apply(...)
+func (m *ImplementationsMocker) Apply(interface{}) {
+	panic("implementations mocker does not support Apply, please use Method(name).Apply(...)")
+}
+
+// Cancel 取消所有方法的 Mock
+func (m *ImplementationsMocker) Cancel() {
+	for _, v := range m.methods {
+		v.Cancel()
+	}
+	m.methods = make(map[string]*ImplMethodMocker, 8)
+}
+
+// Canceled 是否已经被取消
+func (m *ImplementationsMocker) Canceled() bool {
+	for _, v := range m.methods {
+		if !v.Canceled() {
+			return false
+		}
+	}
+	return true
+}
+
+// ImplMethodMocker 接口的所有实现类的方法 Mock
+// 方法模板的第一个参数为接口类型, 用于接收实现类的接收体; 后续的参数原样照抄接口方法
+type ImplMethodMocker struct {
+	*baseMocker
+	iFace   reflect.Type
+	method  string
+	impls   []reflect.Type
+	mockers []*MethodMocker
+	// origins 每个实现类的原方法
+	origins map[reflect.Type]reflect.Value
+}
+
+// String mock 的名称或描述, 方便调试和问题排查
+func (m *ImplMethodMocker) String() string {
+	return fmt.Sprintf("%s.%s(%d implementations)", m.iFace.String(), m.method, len(m.impls))
+}
+
+// Apply 指定 mock 执行的回调函数
+// callback 的第一个参数为接口类型, 后续的参数原样照抄接口方法, 比如: func(s Store, key string) string
+func (m *ImplMethodMocker) Apply(callback interface{}) {
+	if reflect.TypeOf(callback) != reflect.TypeOf(m.funcDef) {
+		panic(erro.NewIllegalParamTypeError("callback",
+			reflect.TypeOf(callback).String(), reflect.TypeOf(m.funcDef).String()))
+	}
+	m.when = nil
+	m.doApply(callback)
+}
+
+// When 指定条件匹配
+func (m *ImplMethodMocker) When(specArg ...interface{}) *When {
+	if m.when != nil {
+		return m.when.When(specArg...)
+	}
+	when, err := CreateWhen(m, m.funcDef, specArg, nil, true)
+	if err != nil {
+		panic(err)
+	}
+	if err := m.whens(when); err != nil {
+		panic(err)
+	}
+	m.doApply(m.imp)
+	return when
+}
+
+// Return 指定返回值
+func (m *ImplMethodMocker) Return(value ...interface{}) *When {
+	if m.when != nil {
+		return m.when.Return(value...)
+	}
+	when, err := CreateWhen(m, m.funcDef, nil, value, true)
+	if err != nil {
+		panic(err)
+	}
+	if err := m.whens(when); err != nil {
+		panic(err)
+	}
+	m.doApply(m.imp)
+	return when
+}
+
+// Returns 依次按顺序返回值
+func (m *ImplMethodMocker) Returns(values ...interface{}) *When {
+	if m.when != nil {
+		return m.when.Returns(values...)
+	}
+	when, err := CreateWhen(m, m.funcDef, nil, nil, true)
+	if err != nil {
+		panic(err)
+	}
+	if err := m.whens(when); err != nil {
+		panic(err)
+	}
+	m.when.Returns(values...)
+	m.doApply(m.imp)
+	return when
+}
+
+// Origin 指定调用的原函数, originFunc 必须是方法模板函数变量的指针
+// 调用时将根据接收体的实际类型调用对应实现类的原方法, 在 Apply、When、Return 之后调用时会重新应用 mock;
+// 每个实现类的跳板函数由框架动态生成, 无需定义占位函数
+func (m *ImplMethodMocker) Origin(originFunc interface{}) ExportedMocker {
+	v := reflect.ValueOf(originFunc)
+	funcTyp := reflect.TypeOf(m.funcDef)
+	if v.Kind() != reflect.Ptr || v.Type().Elem() != funcTyp {
+		panic(erro.NewIllegalParamTypeError("originFunc", v.Type().String(), "*"+funcTyp.String()))
+	}
+	m.origins = make(map[reflect.Type]reflect.Value, len(m.impls))
+	v.Elem().Set(reflect.MakeFunc(funcTyp, func(args []reflect.Value) []reflect.Value {
+		receiver := args[0].Elem()
+		origin, ok := m.origins[receiver.Type()]
+		if !ok {
+			panic("origin of " + receiver.Type().String() + "." + m.method + " not found")
+		}
+		args = append([]reflect.Value{receiver}, args[1:]...)
+		if funcTyp.IsVariadic() {
+			return origin.Elem().CallSlice(args)
+		}
+		return origin.Elem().Call(args)
+	}))
+	if m.imp != nil && !m.canceled {
+		// 已经应用的 mock 没有生成跳板函数, 需要重新应用
+		m.doApply(m.imp)
+	}
+	return m
+}
+
+// Cancel 取消所有实现类的 Mock
+func (m *ImplMethodMocker) Cancel() {
+	for _, mocker := range m.mockers {
+		mocker.Cancel()
+	}
+	m.mockers = nil
+	m.baseMocker.Cancel()
+}
+
+// doApply 对每一个实现类的方法应用 mock
+func (m *ImplMethodMocker) doApply(imp interface{}) {
+	for _, mocker := range m.mockers {
+		mocker.Cancel()
+	}
+	m.mockers = make([]*MethodMocker, 0, len(m.impls))
+	m.imp = imp
+	m.canceled = false
+
+	callback := reflect.ValueOf(imp)
+	for _, impl := range m.impls {
+		method, ok := impl.MethodByName(m.method)
+		if !ok {
+			continue
+		}
+		mocker := NewMethodMocker(m.pkgName, reflect.Zero(impl).Interface())
+		mocker.Method(m.method)
+		if m.origins != nil {
+			// 每个实现类的方法复用同一个跳板函数, 反复 Apply 和 Reset 不会耗尽占位空间
+			origin, err := proxy.Trampoline(method.Func.Pointer(), method.Type)
+			if err != nil {
+				panic(erro.NewTraceableErrorc("create trampoline of "+mocker.String()+" error", err))
+			}
+			m.origins[impl] = reflect.ValueOf(origin)
+			mocker.Origin(origin)
+		}
+		mocker.Apply(reflect.MakeFunc(method.Type, func(args []reflect.Value) []reflect.Value {
+			receiver := reflect.New(m.iFace).Elem()
+			receiver.Set(args[0])
+			args = append([]reflect.Value{receiver}, args[1:]...)
+			if callback.Type().IsVariadic() {
+				return callback.CallSlice(args)
+			}
+			return callback.Call(args)
+		}).Interface())
+		m.mockers = append(m.mockers, mocker)
+	}
+	logger.Consolefc(logger.DebugLevel, "mocker [%s] apply.", logger.Caller(6), m.String())
+}
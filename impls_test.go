@@ -0,0 +1,116 @@
+// Package mocker_test 对 mocker 包的测试
+// 当前文件实现了对 impls.go 的单测
+package mocker_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	mocker "github.com/tencent/goom"
+	"github.com/tencent/goom/erro"
+	"github.com/tencent/goom/test"
+)
+
+// TestUnitImplsTestSuite 测试入口
+func TestUnitImplsTestSuite(t *testing.T) {
+	suite.Run(t, new(implsMockerTestSuite))
+}
+
+type implsMockerTestSuite struct {
+	suite.Suite
+}
+
+// TestUnitImplsReturn 测试接口所有实现类的方法 mock return
+func (s *implsMockerTestSuite) TestUnitImplsReturn() {
+	s.Run("success", func() {
+		var stores = []test.Store{&test.MemStore{}, test.FileStore{}}
+
+		mock := mocker.Create()
+		impls := mock.AllImplementations((*test.Store)(nil))
+		s.Contains(impls.Implementations(), reflect.TypeOf(&test.MemStore{}), "impls check")
+		s.Contains(impls.Implementations(), reflect.TypeOf(test.FileStore{}), "impls check")
+
+		impls.Method("Get").When("k").Return("fake")
+		for _, store := range stores {
+			s.Equal("fake", store.Get("k"), "impls mock check")
+		}
+
+		mock.Reset()
+		s.Equal("mem:k", stores[0].Get("k"), "impls mock reset check")
+		s.Equal("file:k", stores[1].Get("k"), "impls mock reset check")
+	})
+}
+
+// TestUnitImplsApply 测试接口所有实现类的方法 mock apply 及调用原方法
+func (s *implsMockerTestSuite) TestUnitImplsApply() {
+	s.Run("success", func() {
+		var stores = []test.Store{&test.MemStore{}, test.FileStore{}}
+
+		mock := mocker.Create()
+		defer mock.Reset()
+
+		var origin func(test.Store, string) string
+		m := mock.AllImplementations((*test.Store)(nil)).Method("Get")
+		m.Origin(&origin)
+		m.Apply(func(store test.Store, key string) string {
+			return "fake-" + origin(store, key)
+		})
+		s.Equal("fake-mem:k", stores[0].Get("k"), "impls apply check")
+		s.Equal("fake-file:k", stores[1].Get("k"), "impls apply check")
+	})
+}
+
+// TestUnitImplsOriginAfterApply 测试 apply 之后指定原方法, 以及方法不存在时的错误
+func (s *implsMockerTestSuite) TestUnitImplsOriginAfterApply() {
+	s.Run("success", func() {
+		var stores = []test.Store{&test.MemStore{}, test.FileStore{}}
+
+		mock := mocker.Create()
+		defer mock.Reset()
+
+		var origin func(test.Store, string) string
+		impls := mock.AllImplementations((*test.Store)(nil))
+		m := impls.Method("Get")
+		m.Apply(func(store test.Store, key string) string {
+			return "fake-" + origin(store, key)
+		})
+		m.Origin(&origin)
+		s.Equal("fake-mem:k", stores[0].Get("k"), "impls origin after apply check")
+		s.Equal("fake-file:k", stores[1].Get("k"), "impls origin after apply check")
+
+		var expectErr error
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					expectErr, _ = err.(error)
+				}
+			}()
+			impls.Method("None")
+		}()
+		s.IsType(&erro.FuncNotFound{}, expectErr, "method not found check")
+	})
+}
+
+// TestUnitImplsApplyRepeat 测试反复 apply 和 reset 调用原方法的 mock
+func (s *implsMockerTestSuite) TestUnitImplsApplyRepeat() {
+	s.Run("success", func() {
+		var stores = []test.Store{&test.MemStore{}, test.FileStore{}}
+		for i := 0; i < 100; i++ {
+			mock := mocker.Create()
+			var origin func(test.Store, string) string
+			m := mock.AllImplementations((*test.Store)(nil)).Method("Get")
+			m.Origin(&origin)
+			m.Apply(func(store test.Store, key string) string {
+				return "fake-" + origin(store, key)
+			})
+			m.Apply(func(store test.Store, key string) string {
+				return "again-" + origin(store, key)
+			})
+			s.Equal("again-mem:k", stores[0].Get("k"), "impls apply check")
+			s.Equal("again-file:k", stores[1].Get("k"), "impls apply check")
+			mock.Reset()
+			s.Equal("mem:k", stores[0].Get("k"), "impls mock reset check")
+		}
+	})
+}
@@ -0,0 +1,250 @@
+// Package mocker 定义了 mock 的外层用户使用 API 定义,
+// 包括函数、方法、接口、未导出函数(或方法的)的 Mocker 的实现。
+// 当前文件实现了按接收体实例进行方法 mock 的能力:
+// 只有接收体为指定实例的调用才会被 mock, 其它实例的调用将通过跳板函数调用原方法。
+package mocker
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/tencent/goom/erro"
+	"github.com/tencent/goom/internal/logger"
+	"github.com/tencent/goom/internal/proxy"
+)
+
+// InstanceMocker 指定接收体实例的方法 Mocker
+type InstanceMocker struct {
+	cached   *CachedMethodMocker
+	instance interface{}
+	mockers  map[string]*InstanceMethodMocker
+}
+
+// Method 设置结构体的方法名
+func (m *InstanceMocker) Method(name string) ExportedMocker {
+	if mocker, ok := m.mockers[name]; ok && !mocker.Canceled() {
+		return mocker
+	}
+	dispatcher := m.cached.dispatcher(name)
+	mocker := &InstanceMethodMocker{
+		baseMocker: newBaseMocker(m.cached.pkgName),
+		dispatcher: dispatcher,
+		instance:   m.instance,
+		receiver:   reflect.ValueOf(m.instance).Pointer(),
+	}
+	mocker.funcDef = dispatcher.methodIns
+	m.mockers[name] = mocker
+	return mocker
+}
+
+// Cancel 取消该实例所有方法的 mock
+func (m *InstanceMocker) Cancel() {
+	for _, v := range m.mockers {
+		v.Cancel()
+	}
+}
+
+// InstanceMethodMocker 指定接收体实例的单个方法 Mocker
+type InstanceMethodMocker struct {
+	*baseMocker
+	dispatcher *instanceDispatcher
+	instance   interface{}
+	receiver   uintptr
+}
+
+// String mock 的名称或描述, 方便调试和问题排查
+func (m *InstanceMethodMocker) String() string {
+	return fmt.Sprintf("%s@%p", m.dispatcher.mocker.String(), m.instance)
+}
+
+// Apply 指定 mock 执行的回调函数, 只对接收体为指定实例的调用生效
+// 方法的参数签名写法比如: func(s *Struct, arg1, arg2 type), 其中第一个参数必须是接收体类型
+func (m *InstanceMethodMocker) Apply(callback interface{}) {
+	// 回调通过反射调用, 签名必须和方法模板完全一致
+	if funcTyp := reflect.TypeOf(m.funcDef); reflect.TypeOf(callback) != funcTyp {
+		panic(erro.NewIllegalParamTypeError("callback", fmt.Sprintf("%T", callback), funcTyp.String()))
+	}
+	m.when = nil
+	m.doApply(callback)
+}
+
+// When 指定条件匹配
+func (m *InstanceMethodMocker) When(specArg ...interface{}) *When {
+	if m.when != nil {
+		return m.when.When(specArg...)
+	}
+	when, err := CreateWhen(m, m.funcDef, specArg, nil, true)
+	if err != nil {
+		panic(err)
+	}
+	if err := m.whens(when); err != nil {
+		panic(err)
+	}
+	m.doApply(m.imp)
+	return when
+}
+
+// Return 指定返回值
+func (m *InstanceMethodMocker) Return(value ...interface{}) *When {
+	if m.when != nil {
+		return m.when.Return(value...)
+	}
+	when, err := CreateWhen(m, m.funcDef, nil, value, true)
+	if err != nil {
+		panic(err)
+	}
+	if err := m.whens(when); err != nil {
+		panic(err)
+	}
+	m.doApply(m.imp)
+	return when
+}
+
+// Returns 依次按顺序返回值
+func (m *InstanceMethodMocker) Returns(values ...interface{}) *When {
+	if m.when != nil {
+		return m.when.Returns(values...)
+	}
+	when, err := CreateWhen(m, m.funcDef, nil, nil, true)
+	if err != nil {
+		panic(err)
+	}
+	if err := m.whens(when); err != nil {
+		panic(err)
+	}
+	m.when.Returns(values...)
+	m.doApply(m.imp)
+	return when
+}
+
+// Origin 指定调用的原函数, originFunc 必须是方法模板函数变量的指针
+// 跳板函数由框架动态生成, 无需定义占位函数
+func (m *InstanceMethodMocker) Origin(originFunc interface{}) ExportedMocker {
+	v := reflect.ValueOf(originFunc)
+	funcTyp := reflect.TypeOf(m.funcDef)
+	if v.Kind() != reflect.Ptr || v.Type().Elem() != funcTyp {
+		panic(erro.NewIllegalParamTypeError("originFunc", v.Type().String(), "*"+funcTyp.String()))
+	}
+	v.Elem().Set(reflect.MakeFunc(funcTyp, m.dispatcher.callOrigin))
+	return m
+}
+
+// Cancel 取消 mock, 其它实例的 mock 不受影响
+func (m *InstanceMethodMocker) Cancel() {
+	m.dispatcher.remove(m.receiver)
+	m.when = nil
+	m.imp = nil
+	m.canceled = true
+}
+
+// doApply 将回调函数注册到分发器
+func (m *InstanceMethodMocker) doApply(imp interface{}) {
+	imp, _ = interceptDebugInfo(imp, nil, m)
+	m.imp = imp
+	m.canceled = false
+	m.dispatcher.set(m.receiver, reflect.ValueOf(imp))
+	logger.Consolefc(logger.DebugLevel, "mocker [%s] apply.", logger.Caller(6), m.String())
+}
+
+// instanceDispatcher 按接收体分发同一个方法的 mock
+// 同一个方法只 patch 一次, 未注册的接收体将调用原方法
+type instanceDispatcher struct {
+	mocker    *MethodMocker
+	methodIns interface{}
+	// origin 动态生成的跳板函数变量的指针
+	origin   interface{}
+	handlers map[uintptr]reflect.Value
+	// lock 保护 origin 和 handlers
+	lock sync.RWMutex
+}
+
+// newInstanceDispatcher 创建方法的接收体分发器
+func newInstanceDispatcher(pkgName string, structDef interface{}, name string) *instanceDispatcher {
+	if reflect.TypeOf(structDef).Kind() != reflect.Ptr {
+		panic(erro.NewIllegalParamTypeError("instance", reflect.TypeOf(structDef).String(), "ptr"))
+	}
+	mocker := NewMethodMocker(pkgName, structDef)
+	mocker.Method(name)
+	return &instanceDispatcher{
+		mocker:    mocker,
+		methodIns: mocker.methodIns,
+		handlers:  make(map[uintptr]reflect.Value, 4),
+	}
+}
+
+// set 注册接收体的回调函数, 首次注册时 patch 原方法
+func (d *instanceDispatcher) set(receiver uintptr, callback reflect.Value) {
+	d.lock.Lock()
+	d.handlers[receiver] = callback
+	patched := d.origin != nil && !d.mocker.Canceled()
+	d.lock.Unlock()
+
+	if patched {
+		return
+	}
+	// 同一个方法反复 mock 和取消时复用同一个跳板函数
+	origin, err := proxy.Trampoline(reflect.ValueOf(d.methodIns).Pointer(), reflect.TypeOf(d.methodIns))
+	if err != nil {
+		panic(erro.NewTraceableErrorc("create trampoline of "+d.mocker.String()+" error", err))
+	}
+	d.lock.Lock()
+	d.origin = origin
+	d.lock.Unlock()
+	d.mocker.canceled = false
+	d.mocker.Origin(origin)
+	d.mocker.applyByMethod(d.mocker.structDef, d.mocker.method,
+		reflect.MakeFunc(reflect.TypeOf(d.methodIns), d.dispatch).Interface())
+}
+
+// remove 移除接收体的回调函数, 没有任何接收体时取消 patch
+func (d *instanceDispatcher) remove(receiver uintptr) {
+	d.lock.Lock()
+	delete(d.handlers, receiver)
+	empty := len(d.handlers) == 0
+	d.lock.Unlock()
+
+	if empty {
+		d.cancel()
+	}
+}
+
+// cancel 取消所有接收体的 mock
+func (d *instanceDispatcher) cancel() {
+	d.lock.Lock()
+	d.handlers = make(map[uintptr]reflect.Value, 4)
+	origin := d.origin
+	d.origin = nil
+	d.lock.Unlock()
+	if origin != nil {
+		d.mocker.Cancel()
+	}
+}
+
+// dispatch 根据接收体分发调用
+func (d *instanceDispatcher) dispatch(args []reflect.Value) []reflect.Value {
+	d.lock.RLock()
+	callback, ok := d.handlers[args[0].Pointer()]
+	d.lock.RUnlock()
+	if !ok {
+		return d.callOrigin(args)
+	}
+	if callback.Type().IsVariadic() {
+		return callback.CallSlice(args)
+	}
+	return callback.Call(args)
+}
+
+// callOrigin 调用原方法
+func (d *instanceDispatcher) callOrigin(args []reflect.Value) []reflect.Value {
+	d.lock.RLock()
+	origin := reflect.ValueOf(d.origin)
+	d.lock.RUnlock()
+	if !origin.IsValid() {
+		panic(erro.NewIllegalStatusError(d.mocker.String(), "origin is not available before apply"))
+	}
+	if origin.Elem().Type().IsVariadic() {
+		return origin.Elem().CallSlice(args)
+	}
+	return origin.Elem().Call(args)
+}
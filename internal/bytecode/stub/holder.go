@@ -4,6 +4,7 @@ package stub
 import (
 	"errors"
 	"reflect"
+	"sync"
 	"sync/atomic"
 	"unsafe"
 
@@ -78,3 +79,38 @@ func acquireFromHolder(len int) (uintptr, *[]byte, error) {
 	}))
 	return placeholder, bytes, nil
 }
+
+// AcquireTrampoline 从占位函数中获取一段可执行空间, 用于动态生成跳板函数
+// 跳板函数必须位于占位函数区段内, 以保证调用栈回溯时能找到其所属的函数
+func AcquireTrampoline(len int) (uintptr, error) {
+	addr, _, err := acquireFromHolder(len)
+	return addr, err
+}
+
+// reusable 按 key 复用的跳板函数空间
+type reusable struct {
+	addr uintptr
+	len  int
+}
+
+var (
+	// reusables key 到已分配的跳板函数空间的映射
+	reusables    = make(map[interface{}]reusable)
+	reusableLock sync.Mutex
+)
+
+// AcquireTrampolineFor 获取 key 对应的跳板函数空间, 同一个 key 再次获取时复用之前分配的空间, 空间不足时重新分配
+// 占位函数的空间是固定大小的且无法回收, 对同一个目标反复 patch 时需要使用同一个 key, 避免空间耗尽
+func AcquireTrampolineFor(key interface{}, len int) (uintptr, error) {
+	reusableLock.Lock()
+	defer reusableLock.Unlock()
+	if r, ok := reusables[key]; ok && r.len >= len {
+		return r.addr, nil
+	}
+	addr, err := AcquireTrampoline(len)
+	if err != nil {
+		return 0, err
+	}
+	reusables[key] = reusable{addr: addr, len: len}
+	return addr, nil
+}
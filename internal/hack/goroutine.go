@@ -0,0 +1,26 @@
+package hack
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+)
+
+// goroutinePrefix 协程栈信息的开头, 比如: goroutine 18 [running]:
+var goroutinePrefix = []byte("goroutine ")
+
+// GoroutineID 获取当前协程的 ID
+// 运行时没有公开协程 ID, 这里从当前协程的栈信息中解析, 有一定的性能开销, 不要在热点路径中使用
+func GoroutineID() int64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	buf = bytes.TrimPrefix(buf, goroutinePrefix)
+	if i := bytes.IndexByte(buf, ' '); i > 0 {
+		buf = buf[:i]
+	}
+	id, err := strconv.ParseInt(string(buf), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return id
+}
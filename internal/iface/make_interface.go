@@ -59,8 +59,8 @@ type PContext struct {
 	originIface *hack.Iface
 	// originIfaceValue 原始接口值
 	originIfaceValue *hack.Iface
-	// proxyFunc 代理函数, 需要内存持续持有
-	proxyFunc reflect.Value
+	// proxyFuncs 代理函数, 需要内存持续持有
+	proxyFuncs []reflect.Value
 	// canceled 是否已经被取消
 	canceled bool
 }
@@ -94,6 +94,26 @@ func MakeInterface(ctx *IContext, funcTabIndex int, itabFunc uintptr, typ reflec
 	}
 }
 
+// MethodIndex 查找接口方法在方法表(itab.fun)中的位置, 包括未导出方法
+// 接口类型的 Method(i) 顺序和 itab 方法表的顺序一致, 均按方法名排序
+func MethodIndex(typ reflect.Type, name string) (int, bool) {
+	for i := 0; i < typ.NumMethod(); i++ {
+		if typ.Method(i).Name == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// MethodNames 返回接口的所有方法名, 包括未导出方法
+func MethodNames(typ reflect.Type) []string {
+	names := make([]string, 0, typ.NumMethod())
+	for i := 0; i < typ.NumMethod(); i++ {
+		names = append(names, typ.Method(i).Name)
+	}
+	return names
+}
+
 // BackUpTo 备份缓存 iface 指针到 IContext 中
 func BackUpTo(ctx *IContext, iface unsafe.Pointer) {
 	if ctx.p.originIfaceValue == nil {
@@ -125,7 +145,7 @@ func GenCallableMethod(ctx *IContext, apply interface{}, proxy PFunc) uintptr {
 		}
 		mockFuncPtr := (*hack.Value)(unsafe.Pointer(&mockFunc)).Ptr
 		methodCaller, err = MakeMethodCallerWithCtx(mockFuncPtr, callStub)
-		ctx.p.proxyFunc = mockFunc
+		ctx.p.proxyFuncs = append(ctx.p.proxyFuncs, mockFunc)
 	}
 
 	if err != nil {
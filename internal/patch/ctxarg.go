@@ -0,0 +1,70 @@
+package patch
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// CheckCtxArg 检查闭包上下文能否作为第一个参数传给代理函数
+// 闭包上下文通过将整数参数寄存器依次后移一位再放入第一个参数寄存器的方式传递,
+// 只有在插入一个指针参数之后原有参数的寄存器或栈分配不变时才能这样传递
+func CheckCtxArg(funcTyp reflect.Type) error {
+	if !regABI {
+		return fmt.Errorf("closure ctx argument requires register-based calling convention")
+	}
+	origin := assignArgs(funcTyp, 0)
+	withCtx := assignArgs(funcTyp, 1)
+	for i := range origin {
+		if origin[i] != withCtx[i] {
+			return fmt.Errorf("closure %s has too many arguments to pass ctx as the first argument", funcTyp)
+		}
+	}
+	return nil
+}
+
+// assignArgs 按照寄存器调用约定模拟参数分配, 返回每个参数是否分配到寄存器
+// usedInts 已经占用的整数寄存器数量
+func assignArgs(funcTyp reflect.Type, usedInts int) []bool {
+	res := make([]bool, funcTyp.NumIn())
+	ints, floats := usedInts, 0
+	for i := 0; i < funcTyp.NumIn(); i++ {
+		curInts, curFloats := ints, floats
+		if assignReg(funcTyp.In(i), &ints, &floats) && ints <= intArgRegs && floats <= floatArgRegs {
+			res[i] = true
+			continue
+		}
+		// 寄存器不足时整个参数分配到栈上
+		ints, floats = curInts, curFloats
+	}
+	return res
+}
+
+// assignReg 累加类型 typ 需要的整数和浮点寄存器数量, 不能分配到寄存器时返回 false
+func assignReg(typ reflect.Type, ints, floats *int) bool {
+	switch typ.Kind() {
+	case reflect.Float32, reflect.Float64:
+		*floats++
+	case reflect.Complex64, reflect.Complex128:
+		*floats += 2
+	case reflect.String, reflect.Interface:
+		*ints += 2
+	case reflect.Slice:
+		*ints += 3
+	case reflect.Array:
+		if typ.Len() > 1 {
+			return false
+		}
+		if typ.Len() == 1 {
+			return assignReg(typ.Elem(), ints, floats)
+		}
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			if !assignReg(typ.Field(i).Type, ints, floats) {
+				return false
+			}
+		}
+	default:
+		*ints++
+	}
+	return true
+}
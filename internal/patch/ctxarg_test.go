@@ -0,0 +1,22 @@
+package patch_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tencent/goom/internal/patch"
+)
+
+// TestCheckCtxArg 测试闭包上下文能否作为第一个参数传递
+func TestCheckCtxArg(t *testing.T) {
+	if err := patch.CheckCtxArg(reflect.TypeOf(func(int, string, float64, [2]int) int { return 0 })); err != nil {
+		t.Fatalf("check ctx arg error: %v", err)
+	}
+	in := make([]reflect.Type, 20)
+	for i := range in {
+		in[i] = reflect.TypeOf(0)
+	}
+	if err := patch.CheckCtxArg(reflect.FuncOf(in, nil, false)); err == nil {
+		t.Fatalf("check ctx arg of too many arguments should fail")
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/tencent/goom/internal/bytecode"
 	"github.com/tencent/goom/internal/bytecode/memory"
+	"github.com/tencent/goom/internal/bytecode/stub"
 	"github.com/tencent/goom/internal/logger"
 )
 
@@ -25,7 +26,8 @@ var errAlreadyPatch = errors.New("already patched")
 // replacementInAddr 要跳转到的函数调用地址
 // replacementCode 要跳转到的函数地址, 与 replacementInAddr 的区别详细可以参考:
 // https://docs.google.com/document/d/1bMwCey-gmqZVTpRax-ESeVuZGmjwbocYs1iHplK-cjo/pub
-func genJumpData(origin, replacementInAddr, replacementCode uintptr) (jumpData []byte, err error) {
+// ctxArg 是否在跳转前将闭包上下文寄存器作为第一个参数传给代理函数
+func genJumpData(origin, replacementInAddr, replacementCode uintptr, ctxArg bool) (jumpData []byte, err error) {
 	defer func() {
 		if e := recover(); e != nil {
 			logger.Errorf("genJumpData origin=%d replacementInAddr=%d error:%s", origin, replacementInAddr, e)
@@ -51,6 +53,11 @@ func genJumpData(origin, replacementInAddr, replacementCode uintptr) (jumpData [
 
 	// 构造跳转到代理函数的指令
 	jumpData = jmpToFunctionValue(origin, replacementInAddr)
+	if ctxArg {
+		if jumpData, err = ctxJumpData(origin, jumpData); err != nil {
+			return nil, err
+		}
+	}
 	// 如果需要织入的跳转指令的长度大于原函数指令长度,则任务是无法织入指令
 	if len(jumpData) >= funcSize {
 		bytecode.PrintInst("origin inst > ", origin, bytecode.PrintShort, logger.InfoLevel)
@@ -60,6 +67,26 @@ func genJumpData(origin, replacementInAddr, replacementCode uintptr) (jumpData [
 	return jumpData, nil
 }
 
+// ctxJumpKey 传递闭包上下文的桩函数的复用 key
+type ctxJumpKey struct {
+	origin uintptr
+}
+
+// ctxJumpData 构造将闭包上下文放入第一个参数寄存器之后再跳转到代理函数的桩函数, 返回跳转到桩函数的指令
+// 原函数头部只织入一条不破坏参数寄存器的跳转, 移动寄存器的指令放在桩函数中, 以减少需要修复的原函数指令;
+// 同一时间一个函数只能有一个 patch, 所以同一个原函数重复 patch 时复用同一段桩函数空间
+func ctxJumpData(origin uintptr, jumpData []byte) ([]byte, error) {
+	code := append(ctxToArg(), jumpData...)
+	addr, err := stub.AcquireTrampolineFor(ctxJumpKey{origin: origin}, len(code))
+	if err != nil {
+		return nil, err
+	}
+	if err = memory.WriteTo(addr, code); err != nil {
+		return nil, err
+	}
+	return jmpToCode(addr), nil
+}
+
 // checkAndReadOriginBytes 检查原函数是否已经 patch 过, 并且发挥原函数的字节码数组
 func checkAndReadOriginBytes(origin uintptr, jumpDataLen int) ([]byte, error) {
 	// 读取原始指令
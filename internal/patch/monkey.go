@@ -89,6 +89,28 @@ func PtrTrampoline(originPtr uintptr, replacement, trampoline interface{}) (*Gua
 	return patch.Guard(), nil
 }
 
+// PtrTrampolineCtx 直接将闭包跳转的新函数(指定跳板函数), 闭包上下文作为第一个参数传给新函数
+// originPtr 原始闭包地址
+// replacement 代理函数, 第一个参数为闭包上下文, 其余参数和返回值与原闭包一致
+// trampoline 跳板函数地址(可不指定,传 nil)
+func PtrTrampolineCtx(originPtr uintptr, replacement, trampoline interface{}) (*Guard, error) {
+	patch := &patch{
+		replacement: replacement,
+		trampoline:  trampoline,
+
+		replacementValue: reflect.ValueOf(replacement),
+
+		originPtr: originPtr,
+		ctxArg:    true,
+	}
+
+	err := patch.unsafePatchPtr()
+	if err != nil {
+		return nil, err
+	}
+	return patch.Guard(), nil
+}
+
 // InstanceMethod replaces an instance method methodName for the type target with replacementValue
 // Replacement should expect the receiver (of type target) as the first argument
 func InstanceMethod(originType reflect.Type, methodName string, replacement interface{}) (*Guard, error) {
@@ -76,3 +76,66 @@ func relative(from uintptr, to uintptr) bool {
 func checkAlreadyPatch(origin []byte) bool {
 	return origin[0] == nopOpcode
 }
+
+// jmpToCode Assembles a jump to code, keeping the closure context register(rdx)
+// 使用 scratch 寄存器 r12, 不会破坏参数寄存器
+func jmpToCode(to uintptr) []byte {
+	return []byte{
+		0x90, // NOP
+		0x49, 0xBC,
+		byte(to),
+		byte(to >> 8),
+		byte(to >> 16),
+		byte(to >> 24),
+		byte(to >> 32),
+		byte(to >> 40),
+		byte(to >> 48),
+		byte(to >> 56),   // movabs r12,to
+		0x41, 0xFF, 0xE4, // jmp r12
+	}
+}
+
+// intArgRegs 整数参数寄存器的数量, floatArgRegs 浮点参数寄存器的数量
+const (
+	intArgRegs   = 9
+	floatArgRegs = 15
+)
+
+// intArgRegOrder 整数参数寄存器的分配顺序: rax, rbx, rcx, rdi, rsi, r8, r9, r10, r11
+var intArgRegOrder = []byte{0, 3, 1, 7, 6, 8, 9, 10, 11}
+
+// ctxReg 闭包上下文寄存器: rdx
+const ctxReg = 2
+
+// movReg Assembles a move from register src to register dst
+func movReg(dst, src byte) []byte {
+	rex := byte(0x48)
+	if src >= 8 {
+		rex |= 0x04
+	}
+	if dst >= 8 {
+		rex |= 0x01
+	}
+	return []byte{rex, 0x89, 0xC0 | (src&7)<<3 | dst&7} // mov dst,src
+}
+
+// ctxToArg Assembles a shift of the integer argument registers and a move of the closure context register(rdx)
+// to the first argument register(rax)
+func ctxToArg() []byte {
+	res := make([]byte, 0, 3*len(intArgRegOrder))
+	for i := len(intArgRegOrder) - 1; i > 0; i-- {
+		res = append(res, movReg(intArgRegOrder[i], intArgRegOrder[i-1])...)
+	}
+	return append(res, movReg(intArgRegOrder[0], ctxReg)...)
+}
+
+// ArgToCtxJump Assembles a move of the first argument register(rax) to the closure context register(rdx),
+// a shift back of the integer argument registers and a jump to code
+func ArgToCtxJump(to uintptr) []byte {
+	res := make([]byte, 0, 3*len(intArgRegOrder)+14)
+	res = append(res, movReg(ctxReg, intArgRegOrder[0])...)
+	for i := 0; i < len(intArgRegOrder)-1; i++ {
+		res = append(res, movReg(intArgRegOrder[i], intArgRegOrder[i+1])...)
+	}
+	return append(res, jmpToCode(to)...)
+}
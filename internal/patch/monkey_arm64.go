@@ -86,3 +86,68 @@ func checkAlreadyPatch(origin []byte) bool {
 	}
 	return true
 }
+
+// jmpToCode Assembles a jump to code, keeping the closure context register(x26)
+// 使用 scratch 寄存器 x17, 不会破坏参数寄存器
+func jmpToCode(to uintptr) []byte {
+	res := make([]byte, 0, 24)
+	res = append(res, nopOpcode...)
+	res = append(res, movImmTo(17, to)...)
+	res = append(res, []byte{0x20, 0x02, 0x1F, 0xD6}...) // BR x17
+	return res
+}
+
+// intArgRegs 整数参数寄存器的数量, floatArgRegs 浮点参数寄存器的数量
+const (
+	intArgRegs   = 16
+	floatArgRegs = 16
+)
+
+// ctxReg 闭包上下文寄存器: x26
+const ctxReg = 26
+
+// movReg Assembles a move from register xm to register xd
+func movReg(d, m uint32) []byte {
+	inst := 0xAA0003E0 | m<<16 | d // MOV xd, xm
+	return []byte{byte(inst), byte(inst >> 8), byte(inst >> 16), byte(inst >> 24)}
+}
+
+// ctxToArg Assembles a shift of the integer argument registers(x0-x15) and a move of the closure context
+// register(x26) to the first argument register(x0)
+func ctxToArg() []byte {
+	res := make([]byte, 0, 4*intArgRegs)
+	for i := uint32(intArgRegs - 1); i > 0; i-- {
+		res = append(res, movReg(i, i-1)...)
+	}
+	return append(res, movReg(0, ctxReg)...)
+}
+
+// ArgToCtxJump Assembles a move of the first argument register(x0) to the closure context register(x26),
+// a shift back of the integer argument registers(x0-x15) and a jump to code
+func ArgToCtxJump(to uintptr) []byte {
+	res := make([]byte, 0, 4*intArgRegs+24)
+	res = append(res, movReg(ctxReg, 0)...)
+	for i := uint32(0); i < intArgRegs-1; i++ {
+		res = append(res, movReg(i, i+1)...)
+	}
+	return append(res, jmpToCode(to)...)
+}
+
+// movImmTo Assembles MOVZ/MOVK instructions to load a 64-bit immediate into register rd
+func movImmTo(rd uint32, val uintptr) []byte {
+	res := make([]byte, 0, 16)
+	for shift := 0; shift < 4; shift++ {
+		opc := _0b11 // MOVK
+		if shift == 0 {
+			opc = _0b10 // MOVZ
+		}
+		var m = rd                               // rd
+		m |= uint32(val>>(16*shift)&0xFFFF) << 5 // imm16
+		m |= uint32(shift&3) << 21               // hw
+		m |= _0b100101 << 23                     // const
+		m |= uint32(opc&0x3) << 29               // opc
+		m |= _0b1 << 31                          // sf
+		res = append(res, byte(m), byte(m>>8), byte(m>>16), byte(m>>24))
+	}
+	return res
+}
@@ -46,6 +46,9 @@ type patch struct {
 	originBytes []byte
 	jumpBytes   []byte
 
+	// ctxArg 跳转前是否将闭包上下文作为第一个参数传给代理函数
+	ctxArg bool
+
 	guard *Guard
 }
 
@@ -109,7 +112,7 @@ func (p *patch) replaceFunc() error {
 	patches[p.originPtr] = p
 
 	replacementInAddr := (uintptr)(bytecode.GetPtr(p.replacementValue))
-	jumpData, err := genJumpData(p.originPtr, replacementInAddr, p.replacementPtr)
+	jumpData, err := genJumpData(p.originPtr, replacementInAddr, p.replacementPtr, p.ctxArg)
 	if err != nil {
 		if errors.Unwrap(err) == errAlreadyPatch {
 			if pc, ok := patches[p.originPtr]; ok {
@@ -0,0 +1,8 @@
+//go:build !((amd64 && go1.17) || (arm64 && go1.18))
+// +build !amd64 !go1.17
+// +build !arm64 !go1.18
+
+package patch
+
+// regABI 是否使用基于寄存器的调用约定
+const regABI = false
@@ -0,0 +1,76 @@
+package proxy
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/tencent/goom/internal/bytecode/memory"
+	"github.com/tencent/goom/internal/bytecode/stub"
+	"github.com/tencent/goom/internal/logger"
+	"github.com/tencent/goom/internal/patch"
+	"github.com/tencent/goom/internal/unexports2"
+)
+
+// restoreCtxKey 恢复闭包上下文的桩函数的复用 key
+type restoreCtxKey struct {
+	origin uintptr
+}
+
+// Closure 通过闭包的符号名生成代理函数
+// withCtx 为 true 时, 闭包上下文(捕获变量所在的对象地址)作为第一个参数传给代理函数,
+// 返回的 origin 为调用原闭包的函数变量指针, 其第一个参数为闭包上下文, 调用时会恢复闭包上下文;
+// withCtx 为 false 时不传递闭包上下文, 返回的 origin 为 nil
+// @param funcName 闭包的符号名称, 比如: github.com/xxx/yyy.Handler.func1
+// @param proxyFunc 代理函数实现, withCtx 为 true 时第一个参数为 unsafe.Pointer 类型的闭包上下文
+// @param funcTyp 闭包的函数类型
+// @param withCtx 是否传递闭包上下文
+func Closure(funcName string, proxyFunc interface{}, funcTyp reflect.Type,
+	withCtx bool) (*patch.Guard, interface{}, error) {
+	originFuncPtr, err := unexports2.FindFuncByName(funcName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if withCtx {
+		if err = patch.CheckCtxArg(funcTyp); err != nil {
+			return nil, nil, err
+		}
+	}
+	trampoline, err := Trampoline(originFuncPtr, funcTyp)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	logger.Info("start closure proxy funcName=", funcName)
+	if !withCtx {
+		patchGuard, err := patch.PtrTrampoline(originFuncPtr, proxyFunc, trampoline)
+		if err != nil {
+			logger.Error("closure proxy fail funcName=", funcName, ":", err)
+			return nil, nil, err
+		}
+		logger.Info("closure proxy ok, funcName=", funcName)
+		return patchGuard, nil, nil
+	}
+	patchGuard, err := patch.PtrTrampolineCtx(originFuncPtr, proxyFunc, trampoline)
+	if err != nil {
+		logger.Error("closure proxy fail funcName=", funcName, ":", err)
+		return nil, nil, err
+	}
+
+	// 构造从第一个参数恢复闭包上下文之后跳转到原闭包的桩函数, 同一个闭包重复 mock 时复用同一段桩函数空间
+	code := patch.ArgToCtxJump(patchGuard.FixOriginFunc())
+	addr, err := stub.AcquireTrampolineFor(restoreCtxKey{origin: originFuncPtr}, len(code))
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = memory.WriteTo(addr, code); err != nil {
+		return nil, nil, err
+	}
+	origin := reflect.New(reflect.TypeOf(proxyFunc))
+	if _, err = unexports2.CreateFuncForCodePtr(origin.Interface(), addr); err != nil {
+		return nil, nil, err
+	}
+
+	logger.Debug("origin ptr is:", fmt.Sprintf("0x%x", patchGuard.FixOriginFunc()))
+	logger.Info("closure proxy ok, funcName=", funcName)
+	return patchGuard, origin.Interface(), nil
+}
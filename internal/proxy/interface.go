@@ -31,7 +31,10 @@ func Interface(ifaceVar interface{}, ctx *iface.IContext, method string, imp int
 
 	// check args len match
 	argLen := reflect.TypeOf(imp).NumIn()
-	funcTabIndex := methodIndexOf(typ, method)
+	funcTabIndex, ok := iface.MethodIndex(typ, method)
+	if !ok {
+		return erro.NewFuncNotFoundErrorWithSuggestion(typ.String()+"."+method, iface.MethodNames(typ))
+	}
 	maxLen := typ.Method(funcTabIndex).Type.NumIn()
 	if maxLen >= argLen {
 		cause := erro.NewArgsNotMatchError(imp, argLen, maxLen+1)
@@ -60,18 +63,6 @@ func Interface(ifaceVar interface{}, ctx *iface.IContext, method string, imp int
 	return nil
 }
 
-func methodIndexOf(typ reflect.Type, method string) int {
-	funcTabIndex := 0
-	// 根据方法名称获取到方法的 index
-	for i := 0; i < typ.NumMethod(); i++ {
-		if method == typ.Method(i).Name {
-			funcTabIndex = i
-			break
-		}
-	}
-	return funcTabIndex
-}
-
 // applyIfaceTo 应用到变量
 func applyIfaceTo(ifaceVar *hack.Iface, gen unsafe.Pointer) {
 	// 伪造的 interface 赋值到指针变量
@@ -0,0 +1,31 @@
+package proxy
+
+import (
+	"reflect"
+
+	"github.com/tencent/goom/internal/bytecode/stub"
+	"github.com/tencent/goom/internal/unexports2"
+)
+
+// trampolineSize 动态跳板函数的空间大小, 需要足够容纳被修复的原函数头部指令和跳回原函数的指令
+const trampolineSize = 256
+
+// trampolineKey 跳板函数的复用 key
+type trampolineKey struct {
+	// origin 被 patch 的原函数地址
+	origin uintptr
+}
+
+// Trampoline 根据函数类型获取原函数 origin 的跳板函数, 返回跳板函数变量的指针
+// 同一个原函数重复 patch 时复用同一段跳板函数空间: 同一时间一个函数只能有一个 patch, 且修复的指令只和原函数有关
+func Trampoline(origin uintptr, funcTyp reflect.Type) (interface{}, error) {
+	addr, err := stub.AcquireTrampolineFor(trampolineKey{origin: origin}, trampolineSize)
+	if err != nil {
+		return nil, err
+	}
+	out := reflect.New(funcTyp)
+	if _, err := unexports2.CreateFuncForCodePtr(out.Interface(), addr); err != nil {
+		return nil, err
+	}
+	return out.Interface(), nil
+}
@@ -0,0 +1,262 @@
+package unexports2
+
+import (
+	"debug/dwarf"
+	"fmt"
+	"reflect"
+	"sync"
+	"unsafe"
+
+	"github.com/tencent/goom/erro"
+	"github.com/tencent/goom/internal/hack"
+)
+
+// attrGoRuntimeType Go 链接器扩展的 DWARF 属性, 值为类型对应的运行时类型(*_type)的地址
+const attrGoRuntimeType dwarf.Attr = 0x2904
+
+var (
+	dwarfData      *dwarf.Data
+	dwarfLoadError error
+	dwarfOnce      sync.Once
+	// typeAlignment 运行时类型地址和调试信息中记录值的偏差,
+	// 不同版本的链接器记录的可能是绝对地址, 也可能是相对类型段起始地址的偏移量
+	typeAlignment uintptr
+	// subprograms 函数名到函数条目的索引
+	subprograms map[string]dwarf.Offset
+	// types 类型名到带运行时类型的类型条目的索引
+	types map[string]dwarf.Offset
+	// variables 全局变量名到变量条目的索引
+	variables map[string]dwarf.Offset
+
+	// signatureCache 函数签名缓存
+	signatureCache = make(map[string]reflect.Type, 16)
+	// typeCache 类型缓存
+	typeCache     = make(map[string]reflect.Type, 16)
+	signatureLock sync.Mutex
+)
+
+// loadDWARF 按需加载当前可执行文件的调试信息
+func loadDWARF() (*dwarf.Data, error) {
+	dwarfOnce.Do(func() {
+		dwarfData, dwarfLoadError = osReadDWARFFromExeFile()
+		if dwarfLoadError != nil {
+			dwarfLoadError = erro.NewTraceableErrorc("load dwarf error: "+dwarfLoadError.Error(), erro.DwarfFlags)
+			return
+		}
+		dwarfLoadError = buildIndex(dwarfData)
+	})
+	return dwarfData, dwarfLoadError
+}
+
+// FuncSignature 从调试信息(DWARF)中读取函数(或方法)的签名
+// name 函数的符号名称, 比如: github.com/xxx/yyy.foo、github.com/xxx/yyy.(*fake).call;
+// 方法的第一个参数为接收体, 可变参数按照切片参数处理
+func FuncSignature(name string) (reflect.Type, error) {
+	signatureLock.Lock()
+	defer signatureLock.Unlock()
+	if typ, ok := signatureCache[name]; ok {
+		return typ, nil
+	}
+
+	data, err := loadDWARF()
+	if err != nil {
+		return nil, err
+	}
+	off, ok := subprograms[name]
+	if !ok {
+		return nil, erro.NewFuncNotFoundError(name)
+	}
+	r := data.Reader()
+	r.Seek(off)
+	if _, err = r.Next(); err != nil {
+		return nil, err
+	}
+	typ, err := readSignature(data, r)
+	if err != nil {
+		return nil, fmt.Errorf("read signature of %s error: %w", name, err)
+	}
+	signatureCache[name] = typ
+	return typ, nil
+}
+
+// readSignature 读取函数的参数和返回值列表, 构造函数类型
+func readSignature(data *dwarf.Data, r *dwarf.Reader) (reflect.Type, error) {
+	in := make([]reflect.Type, 0, 4)
+	out := make([]reflect.Type, 0, 2)
+	for {
+		entry, err := r.Next()
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil || entry.Tag == 0 {
+			break
+		}
+		if entry.Children {
+			r.SkipChildren()
+		}
+		if entry.Tag != dwarf.TagFormalParameter {
+			continue
+		}
+		off, ok := entry.Val(dwarf.AttrType).(dwarf.Offset)
+		if !ok {
+			return nil, fmt.Errorf("param %v has no type", entry.Val(dwarf.AttrName))
+		}
+		typ, err := runtimeType(data, off)
+		if err != nil {
+			return nil, err
+		}
+		if isOut, _ := entry.Val(dwarf.AttrVarParam).(bool); isOut {
+			out = append(out, typ)
+		} else {
+			in = append(in, typ)
+		}
+	}
+	return reflect.FuncOf(in, out, false), nil
+}
+
+// runtimeType 根据 DWARF 类型条目的运行时类型地址构造 reflect.Type
+func runtimeType(data *dwarf.Data, off dwarf.Offset) (reflect.Type, error) {
+	r := data.Reader()
+	r.Seek(off)
+	entry, err := r.Next()
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("type entry at %d not found", off)
+	}
+	addr, ok := entry.Val(attrGoRuntimeType).(uint64)
+	if !ok || addr == 0 {
+		return nil, fmt.Errorf("type %v has no runtime type", entry.Val(dwarf.AttrName))
+	}
+	typAddr := uintptr(addr) + typeAlignment
+	return toType(*(*unsafe.Pointer)(unsafe.Pointer(&typAddr))), nil
+}
+
+// buildIndex 遍历一次调试信息, 建立函数和类型的索引,
+// 并以 int 类型为基准计算运行时类型地址和调试信息中记录值的偏差
+func buildIndex(data *dwarf.Data) error {
+	subprograms = make(map[string]dwarf.Offset, 4096)
+	types = make(map[string]dwarf.Offset, 1024)
+	variables = make(map[string]dwarf.Offset, 1024)
+	r := data.Reader()
+	for {
+		entry, err := r.Next()
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			break
+		}
+		if entry.Tag == dwarf.TagCompileUnit {
+			continue
+		}
+		if entry.Children {
+			r.SkipChildren()
+		}
+		name, _ := entry.Val(dwarf.AttrName).(string)
+		if name == "" {
+			continue
+		}
+		if entry.Tag == dwarf.TagSubprogram {
+			if _, ok := subprograms[name]; !ok {
+				subprograms[name] = entry.Offset
+			}
+			continue
+		}
+		if entry.Tag == dwarf.TagVariable {
+			if _, ok := variables[name]; !ok {
+				variables[name] = entry.Offset
+			}
+			continue
+		}
+		if _, ok := entry.Val(attrGoRuntimeType).(uint64); ok {
+			types[name] = entry.Offset
+		}
+	}
+
+	intOff, ok := types["int"]
+	if !ok {
+		return fmt.Errorf("base type int not found in dwarf")
+	}
+	r.Seek(intOff)
+	entry, err := r.Next()
+	if err != nil {
+		return err
+	}
+	intTyp := reflect.TypeOf(0)
+	typeAlignment = uintptr((*hack.Iface)(unsafe.Pointer(&intTyp)).Data) -
+		uintptr(entry.Val(attrGoRuntimeType).(uint64))
+	return nil
+}
+
+// FindTypeByName 根据类型的全名从调试信息中查找运行时类型, 调试信息不可用时从 typelinks 中查找
+// name 类型的全名, 比如: github.com/xxx/yyy.fake
+func FindTypeByName(name string) (reflect.Type, error) {
+	signatureLock.Lock()
+	defer signatureLock.Unlock()
+	if typ, ok := typeCache[name]; ok {
+		return typ, nil
+	}
+
+	typ, err := findTypeInDWARF(name)
+	if err != nil {
+		if typ = findTypeInTypelinks(name); typ == nil {
+			return nil, erro.NewTraceableErrorc("type not found: "+name, err)
+		}
+	}
+	typeCache[name] = typ
+	return typ, nil
+}
+
+// findTypeInDWARF 从调试信息中查找类型
+func findTypeInDWARF(name string) (reflect.Type, error) {
+	data, err := loadDWARF()
+	if err != nil {
+		return nil, err
+	}
+	off, ok := types[name]
+	if !ok {
+		return nil, erro.NewTypeNotFoundError(name)
+	}
+	return runtimeType(data, off)
+}
+
+// findTypeInTypelinks 从 typelinks 中查找类型, 具名类型通过其指针类型获取
+func findTypeInTypelinks(name string) reflect.Type {
+	for _, typ := range AllTypes() {
+		if typ.Kind() != reflect.Ptr || typ.Elem().Name() == "" {
+			continue
+		}
+		if elem := typ.Elem(); elem.PkgPath()+"."+elem.Name() == name {
+			return elem
+		}
+	}
+	return nil
+}
+
+// VarType 从调试信息(DWARF)中读取全局变量的类型
+// name 变量的符号名称, 比如: github.com/xxx/yyy.varName
+func VarType(name string) (reflect.Type, error) {
+	signatureLock.Lock()
+	defer signatureLock.Unlock()
+	data, err := loadDWARF()
+	if err != nil {
+		return nil, err
+	}
+	off, ok := variables[name]
+	if !ok {
+		return nil, fmt.Errorf("%v: variable not found in dwarf", name)
+	}
+	r := data.Reader()
+	r.Seek(off)
+	entry, err := r.Next()
+	if err != nil {
+		return nil, err
+	}
+	typOff, ok := entry.Val(dwarf.AttrType).(dwarf.Offset)
+	if !ok {
+		return nil, fmt.Errorf("variable %s has no type", name)
+	}
+	return runtimeType(data, typOff)
+}
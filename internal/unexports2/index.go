@@ -0,0 +1,236 @@
+package unexports2
+
+import (
+	"bytes"
+	"debug/gosym"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tencent/goom/erro"
+	"github.com/tencent/goom/internal/logger"
+)
+
+// symbolCacheEnv 符号索引磁盘缓存目录的环境变量, 不设置时不使用磁盘缓存
+const symbolCacheEnv = "GOOM_SYMBOL_CACHE"
+
+// symbolIndexVersion 符号索引的格式版本, 格式变化时需要升级, 避免读取到旧格式的缓存
+const symbolIndexVersion = 2
+
+// buildIDPrefix 非 ELF 格式的二进制中, 构建 ID 以该前缀记录在代码段的起始位置
+var buildIDPrefix = []byte("\xff Go build ID: \"")
+
+// elfBuildIDNote ELF 格式的二进制中, 构建 ID 记录在 Go 的 note 中: 类型 4, 名称 "Go"
+var elfBuildIDNote = []byte("\x04\x00\x00\x00Go\x00\x00")
+
+var (
+	symIndex          *symbolIndex
+	symIndexLoadError error
+	symIndexOnce      sync.Once
+)
+
+// symbolEntry 索引中的符号
+type symbolEntry struct {
+	Name string
+	Addr uint64
+	// Size 符号大小, 符号表中没有记录时为 0
+	Size uint64
+}
+
+// symbolIndex 按名称排序的符号索引, 支持二分查找和前缀查找;
+// 可以按照二进制的构建 ID 缓存到磁盘, 避免每个测试进程都重新解析符号表
+type symbolIndex struct {
+	Version int
+	// Funcs 函数符号
+	Funcs []symbolEntry
+	// Syms 符号表中的数据符号(包括变量), 同名符号按照符号表中的顺序排列
+	Syms []symbolEntry
+	// NoSyms 二进制中没有数据符号(被裁剪掉了符号表)
+	NoSyms bool
+}
+
+// lookup 根据名称查找符号
+func lookup(entries []symbolEntry, name string) (*symbolEntry, bool) {
+	i := sort.Search(len(entries), func(i int) bool {
+		return entries[i].Name >= name
+	})
+	if i < len(entries) && entries[i].Name == name {
+		return &entries[i], true
+	}
+	return nil, false
+}
+
+// lookupPrefix 查找名称以 prefix 开头的所有符号
+func lookupPrefix(entries []symbolEntry, prefix string) []symbolEntry {
+	start := sort.Search(len(entries), func(i int) bool {
+		return entries[i].Name >= prefix
+	})
+	end := start
+	for end < len(entries) && strings.HasPrefix(entries[end].Name, prefix) {
+		end++
+	}
+	return entries[start:end]
+}
+
+// loadSymbolIndex 按需加载符号索引: 优先读取磁盘缓存, 否则解析符号表构建并写入磁盘缓存
+func loadSymbolIndex() (*symbolIndex, error) {
+	symIndexOnce.Do(func() {
+		begin := time.Now()
+		cacheFile := symbolCacheFile()
+		if symIndex = readSymbolIndex(cacheFile); symIndex != nil {
+			logger.Consolef(logger.DebugLevel, "symbol index loaded from cache %s in %v, funcs: %d, syms: %d",
+				cacheFile, time.Since(begin), len(symIndex.Funcs), len(symIndex.Syms))
+		} else if symIndex, symIndexLoadError = buildSymbolIndex(); symIndexLoadError == nil {
+			logger.Consolef(logger.DebugLevel, "symbol index built in %v, funcs: %d, syms: %d",
+				time.Since(begin), len(symIndex.Funcs), len(symIndex.Syms))
+			writeSymbolIndex(cacheFile, symIndex)
+		}
+		if symIndexLoadError == nil && symIndex.NoSyms {
+			varSymbolsError = erro.NewTraceableErrorc("unable to resolve variable symbols from executable file",
+				erro.LdFlags)
+		}
+	})
+	return symIndex, symIndexLoadError
+}
+
+// buildSymbolIndex 解析符号表, 构建符号索引
+func buildSymbolIndex() (*symbolIndex, error) {
+	table, err := GetSymbolTable()
+	if err != nil {
+		return nil, err
+	}
+	index := &symbolIndex{
+		Version: symbolIndexVersion,
+		Funcs:   make([]symbolEntry, 0, len(table.Funcs)),
+		Syms:    make([]symbolEntry, 0, len(table.Syms)),
+		NoSyms:  varSymbolsError != nil,
+	}
+	for i := range table.Funcs {
+		index.Funcs = append(index.Funcs, symbolEntry{Name: table.Funcs[i].Name, Addr: table.Funcs[i].Entry})
+	}
+	for i := range table.Syms {
+		name := table.Syms[i].Name
+		index.Syms = append(index.Syms, symbolEntry{Name: name, Addr: table.Syms[i].Value, Size: symbolSizes[name]})
+	}
+	sortEntries(index.Funcs)
+	sortEntries(index.Syms)
+	return index, nil
+}
+
+// sortEntries 按名称稳定排序, 同名符号保持符号表中的顺序, 查找时返回第一个
+func sortEntries(entries []symbolEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+}
+
+// symbolCacheFile 获取当前二进制的符号索引缓存文件, 未开启磁盘缓存或者获取不到构建 ID 时返回空
+func symbolCacheFile() string {
+	dir := os.Getenv(symbolCacheEnv)
+	if dir == "" {
+		return ""
+	}
+	exePath, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	id, err := readBuildID(exePath)
+	if err != nil {
+		logger.Consolef(logger.DebugLevel, "symbol cache disabled: %v", err)
+		return ""
+	}
+	return filepath.Join(dir, strings.NewReplacer("/", "_", "\\", "_").Replace(id)+".symbols")
+}
+
+// readBuildID 读取二进制的构建 ID, 构建 ID 位于文件头部
+func readBuildID(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	data := make([]byte, 64*1024)
+	n, err := io.ReadFull(file, data)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	data = data[:n]
+
+	if i := bytes.Index(data, buildIDPrefix); i >= 0 {
+		id := data[i+len(buildIDPrefix):]
+		if j := bytes.IndexByte(id, '"'); j > 0 {
+			return string(id[:j]), nil
+		}
+	}
+	if i := bytes.Index(data, elfBuildIDNote); i >= 8 {
+		size := int(binary.LittleEndian.Uint32(data[i-4:]))
+		if start := i + len(elfBuildIDNote); size > 0 && start+size <= len(data) {
+			return string(data[start : start+size]), nil
+		}
+	}
+	return "", fmt.Errorf("go build id not found in %s", path)
+}
+
+// readSymbolIndex 读取符号索引的磁盘缓存, 缓存不存在或者无效时返回 nil
+func readSymbolIndex(cacheFile string) *symbolIndex {
+	if cacheFile == "" {
+		return nil
+	}
+	file, err := os.Open(cacheFile)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	index := &symbolIndex{}
+	if err = gob.NewDecoder(file).Decode(index); err != nil || index.Version != symbolIndexVersion {
+		logger.Consolef(logger.DebugLevel, "invalid symbol cache %s: %v", cacheFile, err)
+		return nil
+	}
+	return index
+}
+
+// writeSymbolIndex 将符号索引写入磁盘缓存, 先写临时文件再重命名, 避免并发的测试进程读取到不完整的文件
+func writeSymbolIndex(cacheFile string, index *symbolIndex) {
+	if cacheFile == "" {
+		return
+	}
+	begin := time.Now()
+	if err := os.MkdirAll(filepath.Dir(cacheFile), 0755); err != nil {
+		logger.Consolef(logger.DebugLevel, "write symbol cache error: %v", err)
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(cacheFile), filepath.Base(cacheFile)+".*")
+	if err != nil {
+		logger.Consolef(logger.DebugLevel, "write symbol cache error: %v", err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	err = gob.NewEncoder(tmp).Encode(index)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), cacheFile)
+	}
+	if err != nil {
+		logger.Consolef(logger.DebugLevel, "write symbol cache error: %v", err)
+		return
+	}
+	logger.Consolef(logger.DebugLevel, "symbol index saved to cache %s in %v", cacheFile, time.Since(begin))
+}
+
+// toSyms 将索引中的符号转换为 gosym.Sym
+func toSyms(entries []symbolEntry) []gosym.Sym {
+	syms := make([]gosym.Sym, 0, len(entries))
+	for _, e := range entries {
+		syms = append(syms, gosym.Sym{Name: e.Name, Value: e.Addr})
+	}
+	return syms
+}
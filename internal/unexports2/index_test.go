@@ -0,0 +1,55 @@
+package unexports2
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestSymbolIndexLookup 测试符号索引的名称查找和前缀查找
+func TestSymbolIndexLookup(t *testing.T) {
+	index, err := loadSymbolIndex()
+	if err != nil {
+		t.Fatalf("load symbol index error: %v", err)
+	}
+	name := "github.com/tencent/goom/internal/unexports2.FindFuncByName"
+	if _, ok := lookup(index.Funcs, name); !ok {
+		t.Errorf("function symbol %s not found", name)
+	}
+	if _, ok := lookup(index.Funcs, name+"NotExists"); ok {
+		t.Errorf("function symbol %sNotExists should not be found", name)
+	}
+	for _, e := range lookupPrefix(index.Funcs, "github.com/tencent/goom/internal/unexports2.Find") {
+		if e.Name == name {
+			return
+		}
+	}
+	t.Errorf("function symbol %s not found by prefix", name)
+}
+
+// TestSymbolIndexCache 测试符号索引按照构建 ID 写入和读取磁盘缓存
+func TestSymbolIndexCache(t *testing.T) {
+	exePath, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := readBuildID(exePath)
+	if err != nil || id == "" {
+		t.Fatalf("read build id error: %v", err)
+	}
+
+	index, err := loadSymbolIndex()
+	if err != nil {
+		t.Fatalf("load symbol index error: %v", err)
+	}
+	cacheFile := filepath.Join(t.TempDir(), id+".symbols")
+	writeSymbolIndex(cacheFile, index)
+	cached := readSymbolIndex(cacheFile)
+	if cached == nil {
+		t.Fatalf("read symbol cache %s failed", cacheFile)
+	}
+	if len(cached.Funcs) != len(index.Funcs) || len(cached.Syms) != len(index.Syms) || cached.NoSyms != index.NoSyms {
+		t.Errorf("Expected cached index [%d, %d] but got [%d, %d]",
+			len(index.Funcs), len(index.Syms), len(cached.Funcs), len(cached.Syms))
+	}
+}
@@ -0,0 +1,98 @@
+package unexports2
+
+import (
+	"debug/gosym"
+	"fmt"
+	"unsafe"
+)
+
+// pcHeader 运行时 pclntab 的头部, 只声明用到的字段
+type pcHeader struct {
+	magic uint32
+}
+
+// moduledata 运行时模块数据(runtime.moduledata)的前缀部分, 字段布局需要和运行时保持一致
+type moduledata struct {
+	pcHeader    *pcHeader
+	funcnametab []byte
+	cutab       []uint32
+	filetab     []byte
+	pctab       []byte
+	pclntable   []byte
+	ftab        []uintptr
+	findfunctab uintptr
+	minpc       uintptr
+	maxpc       uintptr
+	text        uintptr
+	etext       uintptr
+}
+
+// lastmoduledatap 运行时最后加载的模块, 非插件模式下即为主模块
+//
+//go:linkname lastmoduledatap runtime.lastmoduledatap
+var lastmoduledatap *moduledata
+
+// pclntab 版本号, 参考 debug/gosym
+const (
+	go116magic = 0xfffffffa
+	go118magic = 0xfffffff0
+	go120magic = 0xfffffff1
+)
+
+// readRuntimeSymbols 从运行时内存中的 pclntab 读取函数符号表,
+// 二进制被裁剪掉符号表(-ldflags="-s")时, 函数名和入口地址依然可以从 pclntab 中获取;
+// 读取到的函数入口地址即为内存中的真实地址
+func readRuntimeSymbols() (*gosym.Table, error) {
+	md := lastmoduledatap
+	if md == nil || md.pcHeader == nil {
+		return nil, fmt.Errorf("runtime module data not found")
+	}
+	switch magic := md.pcHeader.magic; magic {
+	case go116magic, go118magic, go120magic:
+	default:
+		return nil, fmt.Errorf("unsupported runtime pclntab magic: %#x", magic)
+	}
+
+	lineTable := gosym.NewLineTable(pclntabData(md), uint64(md.text))
+	table, err := gosym.NewTable([]byte{}, lineTable)
+	if err != nil {
+		return nil, err
+	}
+	if len(table.Funcs) == 0 {
+		return nil, fmt.Errorf("no function found in runtime pclntab")
+	}
+	return table, nil
+}
+
+// pclntabData 获取内存中 pclntab 的完整数据, 其长度以各个子表的最大结束地址为准
+func pclntabData(md *moduledata) []byte {
+	start := uintptr(unsafe.Pointer(md.pcHeader))
+	end := start
+	for _, s := range []struct {
+		header *sliceHeader
+		elem   uintptr
+	}{
+		{(*sliceHeader)(unsafe.Pointer(&md.funcnametab)), 1},
+		{(*sliceHeader)(unsafe.Pointer(&md.cutab)), 4},
+		{(*sliceHeader)(unsafe.Pointer(&md.filetab)), 1},
+		{(*sliceHeader)(unsafe.Pointer(&md.pctab)), 1},
+		{(*sliceHeader)(unsafe.Pointer(&md.pclntable)), 1},
+	} {
+		if e := s.header.Data + uintptr(s.header.Len)*s.elem; e > end {
+			end = e
+		}
+	}
+	var data []byte
+	header := (*sliceHeader)(unsafe.Pointer(&data))
+	header.Data = start
+	header.Len = int(end - start)
+	header.Cap = header.Len
+	return data
+}
+
+// sliceHeader 切片的内存结构
+type sliceHeader struct {
+	Data uintptr
+	Len  int
+	Cap  int
+}
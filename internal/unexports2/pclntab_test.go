@@ -0,0 +1,21 @@
+package unexports2
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestRuntimeSymbols 测试从运行时 pclntab 中读取的函数地址和真实地址一致, 不依赖可执行文件的符号表
+func TestRuntimeSymbols(t *testing.T) {
+	table, err := readRuntimeSymbols()
+	if err != nil {
+		t.Fatalf("read runtime symbols error: %v", err)
+	}
+	fn := table.LookupFunc("github.com/tencent/goom/internal/unexports2.FindFuncByName")
+	if fn == nil {
+		t.Fatalf("function symbol not found in runtime pclntab")
+	}
+	if expect := reflect.ValueOf(FindFuncByName).Pointer(); uintptr(fn.Entry) != expect {
+		t.Errorf("Expected entry [%#x] but got [%#x]", expect, fn.Entry)
+	}
+}
@@ -0,0 +1,122 @@
+package unexports2
+
+import (
+	"debug/gosym"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// varSkipPrefixes 非变量符号的前缀, 比如类型元数据、编译器生成的符号等
+var varSkipPrefixes = []string{"type:", "type.", "go:", "go.", "runtime.", "$", "gclocals", "_cgo"}
+
+// varSkipSuffixes 非变量符号的后缀, 比如函数的栈对象、参数信息等元数据
+var varSkipSuffixes = []string{".stkobj", ".arginfo0", ".arginfo1", ".argliveinfo", ".args_stackmap",
+	".opendefer", ".wrapinfo", "..inittask", ".f", ".abi0"}
+
+// FunctionSymbols 返回二进制中所有函数的符号
+func FunctionSymbols() ([]gosym.Sym, error) {
+	index, err := loadSymbolIndex()
+	if err != nil {
+		return nil, err
+	}
+	return toSyms(index.Funcs), nil
+}
+
+// FunctionSymbolsWithPrefix 返回二进制中名称以 prefix 开头的函数的符号
+func FunctionSymbolsWithPrefix(prefix string) ([]gosym.Sym, error) {
+	index, err := loadSymbolIndex()
+	if err != nil {
+		return nil, err
+	}
+	return toSyms(lookupPrefix(index.Funcs, prefix)), nil
+}
+
+// VariableSymbols 返回二进制中所有全局变量的符号
+// 符号表中没有区分变量和其它数据符号, 这里根据名称过滤掉函数和编译器生成的符号
+func VariableSymbols() ([]gosym.Sym, error) {
+	index, err := loadSymbolIndex()
+	if err != nil {
+		return nil, err
+	}
+	syms := make([]gosym.Sym, 0, 1024)
+	for i, s := range index.Syms {
+		if (i > 0 && index.Syms[i-1].Name == s.Name) || !isVarSymbol(s.Name) {
+			continue
+		}
+		if _, isFunc := lookup(index.Funcs, s.Name); isFunc {
+			continue
+		}
+		syms = append(syms, gosym.Sym{Name: s.Name, Value: s.Addr})
+	}
+	return syms, nil
+}
+
+// isVarSymbol 根据名称判断是否是全局变量的符号
+func isVarSymbol(name string) bool {
+	if !strings.Contains(name, ".") || strings.Contains(name, "·") {
+		return false
+	}
+	for _, p := range varSkipPrefixes {
+		if strings.HasPrefix(name, p) {
+			return false
+		}
+	}
+	for _, s := range varSkipSuffixes {
+		if strings.HasSuffix(name, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// symbolNames 获取符号的名称列表, 按照名称排序
+func symbolNames(syms []gosym.Sym) []string {
+	names := make([]string, 0, len(syms))
+	for _, s := range syms {
+		names = append(names, s.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var (
+	// varsByAddr 按地址排序的全局变量符号, 用于根据地址反查变量名
+	varsByAddr     []symbolEntry
+	varsByAddrOnce sync.Once
+)
+
+// FindVarByAddr 根据内存地址反查全局变量的符号名称
+// 返回变量名和地址相对变量起始地址的偏移量(比如结构体字段), 地址不属于任何全局变量时返回 false;
+// 符号表中没有记录变量大小时(非 ELF 格式), 只能匹配变量的起始地址
+func FindVarByAddr(addr uintptr) (string, uintptr, bool) {
+	initAlignment.Do(initAlignmentFunc)
+	index, err := loadSymbolIndex()
+	if err != nil || index.NoSyms {
+		return "", 0, false
+	}
+	varsByAddrOnce.Do(func() {
+		varsByAddr = make([]symbolEntry, 0, len(index.Syms))
+		for _, s := range index.Syms {
+			if isVarSymbol(s.Name) {
+				varsByAddr = append(varsByAddr, s)
+			}
+		}
+		sort.SliceStable(varsByAddr, func(i, j int) bool {
+			return varsByAddr[i].Addr < varsByAddr[j].Addr
+		})
+	})
+
+	target := uint64(addr - varAlignment)
+	i := sort.Search(len(varsByAddr), func(i int) bool {
+		return varsByAddr[i].Addr > target
+	}) - 1
+	if i < 0 {
+		return "", 0, false
+	}
+	s := varsByAddr[i]
+	if s.Addr == target || target < s.Addr+s.Size {
+		return s.Name, uintptr(target - s.Addr), true
+	}
+	return "", 0, false
+}
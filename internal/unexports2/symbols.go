@@ -3,34 +3,54 @@ package unexports2
 import (
 	"debug/gosym"
 	"fmt"
+	"time"
+
+	"github.com/tencent/goom/erro"
+	"github.com/tencent/goom/internal/logger"
 )
 
 var (
 	symTable          *gosym.Table
 	symTableLoadError error
+	// varSymbolsError 变量符号不可用的原因, 比如二进制被裁剪掉了符号表
+	varSymbolsError error
+	// symbolSizes 数据符号的大小, 只有 ELF 格式的符号表中记录了符号大小
+	symbolSizes map[string]uint64
 )
 
-func loadSymbolTable() (table *gosym.Table, err error) {
-	if err = symTableLoadError; err != nil {
-		return
+// loadSymbolTable 加载符号表: 函数符号优先从运行时的 pclntab 中读取, 不依赖可执行文件中的符号表;
+// 变量符号只能从可执行文件的符号表中读取
+func loadSymbolTable() (*gosym.Table, error) {
+	if symTableLoadError != nil || symTable != nil {
+		return symTable, symTableLoadError
 	}
 
-	if table = symTable; table != nil {
-		return
+	table, err := readRuntimeSymbols()
+	if err != nil {
+		// 运行时 pclntab 不可用, 退化为从可执行文件中读取
+		return loadSymbolTableFromExeFile()
 	}
+	fileTable, err := osReadSymbolsFromExeFile()
+	if err == nil && len(fileTable.Syms) > 0 {
+		table.Syms = fileTable.Syms
+	} else {
+		varSymbolsError = erro.NewTraceableErrorc("unable to resolve variable symbols from executable file", erro.LdFlags)
+	}
+	symTable, symTableLoadError = table, nil
+	return symTable, nil
+}
 
-	table, err = osReadSymbolsFromExeFile()
-	symTableLoadError = err
+// loadSymbolTableFromExeFile 从可执行文件中读取函数和变量符号表
+func loadSymbolTableFromExeFile() (*gosym.Table, error) {
+	table, err := osReadSymbolsFromExeFile()
+	if err == nil && table == nil {
+		err = fmt.Errorf("Unknown error: symbol table was nil")
+	}
+	symTable, symTableLoadError = table, err
 	if err != nil {
 		symTable = nil
-	} else if table == nil {
-		err = fmt.Errorf("Unknown error: symbol table was nil")
-	} else {
-		symTable = table
 	}
-
-	symTable = table
-	return
+	return symTable, symTableLoadError
 }
 
 // GetFunctionSymbol returns the symbols for a given function.
@@ -51,41 +71,35 @@ func GetFunctionSymbol(function interface{}) (symbol *gosym.Func, err error) {
 	return
 }
 
-func getFunctionSymbolByName(name string) (symbol *gosym.Func, err error) {
-	var table *gosym.Table
-	if table, err = GetSymbolTable(); err != nil {
-		return
+// getFunctionSymbolByName 从符号索引中查找函数符号
+func getFunctionSymbolByName(name string) (*gosym.Func, error) {
+	index, err := loadSymbolIndex()
+	if err != nil {
+		return nil, err
 	}
-
-	symbol = table.LookupFunc(name)
-	if symbol == nil {
-		err = fmt.Errorf("%v: function symbol not found", name)
+	begin := time.Now()
+	entry, ok := lookup(index.Funcs, name)
+	logger.Consolef(logger.DebugLevel, "lookup function symbol %s in %v", name, time.Since(begin))
+	if !ok {
+		return nil, fmt.Errorf("%v: function symbol not found", name)
 	}
-	return
+	return &gosym.Func{Entry: entry.Addr, Sym: &gosym.Sym{Name: entry.Name, Value: entry.Addr}}, nil
 }
 
-func getVarSymbolByName(name string) (symbol *gosym.Sym, err error) {
-	var table *gosym.Table
-	if table, err = GetSymbolTable(); err != nil {
-		return
+// getVarSymbolByName 从符号索引中查找变量符号
+func getVarSymbolByName(name string) (*gosym.Sym, error) {
+	index, err := loadSymbolIndex()
+	if err != nil {
+		return nil, err
 	}
-
-	symbol = lookupSym(table, name)
-	if symbol == nil {
-		err = fmt.Errorf("%v: variable symbol not found", name)
+	begin := time.Now()
+	entry, ok := lookup(index.Syms, name)
+	logger.Consolef(logger.DebugLevel, "lookup variable symbol %s in %v", name, time.Since(begin))
+	if !ok && varSymbolsError != nil {
+		return nil, erro.NewTraceableErrorc(name+": variable symbol not found", varSymbolsError)
 	}
-	return
-}
-
-// LookupSym returns the text, data, or bss symbol with the given name,
-// or nil if no such symbol is found.
-func lookupSym(t *gosym.Table, name string) *gosym.Sym {
-	// TODO(austin) Maybe make a map
-	for i := range t.Syms {
-		s := &t.Syms[i]
-		if s.Name == name {
-			return s
-		}
+	if !ok {
+		return nil, fmt.Errorf("%v: variable symbol not found", name)
 	}
-	return nil
+	return &gosym.Sym{Name: entry.Name, Value: entry.Addr}, nil
 }
@@ -4,6 +4,7 @@
 package unexports2
 
 import (
+	"debug/dwarf"
 	"debug/gosym"
 	"debug/macho"
 	"fmt"
@@ -70,3 +71,16 @@ func osReadSymbols(reader io.ReaderAt) (*gosym.Table, error) {
 	symTable.Syms = syms
 	return symTable, nil
 }
+
+func osReadDWARFFromExeFile() (*dwarf.Data, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	file, err := macho.Open(exePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return file.DWARF()
+}
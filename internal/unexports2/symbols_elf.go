@@ -4,6 +4,7 @@
 package unexports2
 
 import (
+	"debug/dwarf"
 	"debug/elf"
 	"debug/gosym"
 	"fmt"
@@ -69,13 +70,30 @@ func osReadSymbols(reader io.ReaderAt) (*gosym.Table, error) {
 	}
 
 	syms := make([]gosym.Sym, 0, len(symbols))
+	symbolSizes = make(map[string]uint64, len(symbols))
 	for i := range symbols {
 		syms = append(syms, gosym.Sym{
 			Name:  symbols[i].Name,
 			Value: symbols[i].Value,
 			Type:  symbols[i].Info,
 		})
+		if _, ok := symbolSizes[symbols[i].Name]; !ok {
+			symbolSizes[symbols[i].Name] = symbols[i].Size
+		}
 	}
 	symTable.Syms = syms
 	return symTable, nil
 }
+
+func osReadDWARFFromExeFile() (*dwarf.Data, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	file, err := elf.Open(exePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return file.DWARF()
+}
@@ -4,6 +4,7 @@
 package unexports2
 
 import (
+	"debug/dwarf"
 	"debug/gosym"
 	"debug/pe"
 	"fmt"
@@ -106,3 +107,16 @@ func osReadSymbols(reader io.ReaderAt) (*gosym.Table, error) {
 	symTable.Syms = syms
 	return symTable, err
 }
+
+func osReadDWARFFromExeFile() (*dwarf.Data, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	file, err := pe.Open(exePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return file.DWARF()
+}
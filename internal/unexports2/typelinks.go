@@ -0,0 +1,59 @@
+package unexports2
+
+import (
+	"reflect"
+	"unsafe"
+
+	"github.com/tencent/goom/internal/hack"
+)
+
+// typelinks 获取二进制中登记的所有类型的地址偏移
+//
+//go:linkname typelinks reflect.typelinks
+func typelinks() (sections []unsafe.Pointer, offset [][]int32)
+
+// AllTypes 返回二进制中登记(typelinks)的所有类型
+// 登记的类型只包含指针、chan、map、slice、数组等类型, 具名类型可以通过其指针类型的 Elem 获取
+func AllTypes() []reflect.Type {
+	sections, offsets := typelinks()
+	types := make([]reflect.Type, 0, 1024)
+	for i, base := range sections {
+		for _, off := range offsets[i] {
+			types = append(types, toType(unsafe.Pointer(uintptr(base)+uintptr(off))))
+		}
+	}
+	return types
+}
+
+// AllImplementations 返回二进制中实现了接口 iFace 的所有具体类型
+// 如果值类型已经实现了接口, 则不再返回其指针类型
+func AllImplementations(iFace reflect.Type) []reflect.Type {
+	if iFace.Kind() != reflect.Interface {
+		return nil
+	}
+	visited := make(map[reflect.Type]bool, 32)
+	impls := make([]reflect.Type, 0, 8)
+	for _, t := range AllTypes() {
+		if t.Kind() != reflect.Ptr || t.Elem().Kind() == reflect.Interface || t.Elem().Name() == "" {
+			continue
+		}
+		impl := t
+		if t.Elem().Implements(iFace) {
+			impl = t.Elem()
+		} else if !t.Implements(iFace) {
+			continue
+		}
+		if !visited[impl] {
+			visited[impl] = true
+			impls = append(impls, impl)
+		}
+	}
+	return impls
+}
+
+// toType 将类型数据的地址转换为 reflect.Type
+func toType(typ unsafe.Pointer) reflect.Type {
+	t := reflect.TypeOf(0)
+	(*hack.Iface)(unsafe.Pointer(&t)).Data = typ
+	return t
+}
@@ -0,0 +1 @@
+// 空汇编文件, 允许 typelinks.go 中声明无函数体的 linkname 函数
@@ -35,8 +35,6 @@ func initAlignmentFunc() {
 	varSymTabAddress := uintptr(var1.Value)
 	varMemAddress := reflect.ValueOf(&stubVar).Pointer()
 	varAlignment = varMemAddress - varSymTabAddress
-
-	GetSymbolTable()
 }
 
 // FindFuncByName read the symbol table at runtime
@@ -49,7 +47,14 @@ func FindFuncByName(name string) (uintptr, error) {
 	if erro.CauseBy(err, erro.LdFlags) {
 		panic(err)
 	}
-	return 0, err
+	if _, e := loadSymbolIndex(); e != nil {
+		return 0, err
+	}
+	// 函数名提示只在错误信息输出时计算, 查找不到的探测调用不需要遍历符号表
+	return 0, erro.NewFuncNotFoundErrorWithCandidates(name, func() []string {
+		syms, _ := FunctionSymbols()
+		return symbolNames(syms)
+	})
 }
 
 // FindVarByName read the var address at runtime
@@ -102,3 +107,16 @@ func NewFuncWithCodePtr(typ reflect.Type, codePtr uintptr) reflect.Value {
 	(*hack.Value)(unsafe.Pointer(&funcVal)).Flag = uintptr(reflect.Func)
 	return funcVal
 }
+
+// VarSize 从符号表中读取全局变量的大小, 符号表中没有记录时返回 0
+func VarSize(name string) uintptr {
+	initAlignment.Do(initAlignmentFunc)
+	index, err := loadSymbolIndex()
+	if err != nil {
+		return 0
+	}
+	if entry, ok := lookup(index.Syms, name); ok {
+		return uintptr(entry.Size)
+	}
+	return 0
+}
@@ -54,6 +54,17 @@ type UnExportedMocker interface {
 	Origin(originFunc interface{}) UnExportedMocker
 }
 
+// InferredMocker 可以从调试信息中推导函数签名的未导出函数 mock 接口, 无需调用 As 即可指定返回值
+type InferredMocker interface {
+	UnExportedMocker
+	// When 指定条件匹配, 函数签名从调试信息中推导, 无需调用 As
+	When(specArg ...interface{}) *When
+	// Return 执行返回值, 函数签名从调试信息中推导, 无需调用 As
+	Return(value ...interface{}) *When
+	// Returns 依次按顺序返回值, 函数签名从调试信息中推导, 无需调用 As
+	Returns(values ...interface{}) *When
+}
+
 // baseMocker mocker 基础类型
 type baseMocker struct {
 	pkgName string
@@ -61,8 +72,12 @@ type baseMocker struct {
 	guard   MockGuard
 	funcDef interface{}
 	imp     interface{}
+	// rawImp 用户指定的回调(未经过调试日志和调用屏障的包装), 用于创建调用屏障时重新应用 mock
+	rawImp interface{}
 
 	when *When
+	// barrier 调用屏障, 为 nil 时不拦截调用
+	barrier *Barrier
 	// canceled 是否被取消
 	canceled bool
 }
@@ -159,9 +174,36 @@ func (m *baseMocker) Cancel() {
 	}
 	m.when = nil
 	m.origin = nil
+	if m.barrier != nil {
+		// 放行停在屏障中的调用, 避免协程泄漏
+		m.barrier.ReleaseAll()
+		m.barrier = nil
+	}
 	m.canceled = true
 }
 
+// block 创建调用屏障并重新应用 mock, 还没有指定回调时使用返回零值的回调
+func (m *baseMocker) block(name string, receiver bool, funcTyp reflect.Type, apply func(imp interface{})) *Barrier {
+	if m.barrier != nil {
+		return m.barrier
+	}
+	m.barrier = newBarrier(name, receiver)
+	imp := m.rawImp
+	if imp == nil {
+		imp = zeroImp(funcTyp)
+	}
+	apply(imp)
+	return m.barrier
+}
+
+// interceptBarrier 存在调用屏障时拦截 mock 回调
+func (m *baseMocker) interceptBarrier(imp interface{}) interface{} {
+	if m.barrier == nil {
+		return imp
+	}
+	return m.barrier.intercept(imp)
+}
+
 // Canceled 是否被取消
 func (m *baseMocker) Canceled() bool {
 	return m.canceled
@@ -212,7 +254,7 @@ func (m *MethodMocker) Method(name string) ExportedMocker {
 }
 
 // ExportMethod 导出私有方法
-func (m *MethodMocker) ExportMethod(name string) UnExportedMocker {
+func (m *MethodMocker) ExportMethod(name string) InferredMocker {
 	if name == "" {
 		panic("method is empty")
 	}
@@ -237,6 +279,7 @@ func (m *MethodMocker) ExportMethod(name string) UnExportedMocker {
 // mock 回调函数, 需要和 mock 模板函数的签名保持一致
 // 方法的参数签名写法比如: func(s *Struct, arg1, arg2 type), 其中第一个参数必须是接收体类型
 func (m *MethodMocker) Apply(callback interface{}) {
+	m.when = nil
 	m.doApply(callback)
 }
 
@@ -244,8 +287,17 @@ func (m *MethodMocker) doApply(imp interface{}) {
 	if m.method == "" {
 		panic("method is empty")
 	}
+	m.rawImp = imp
 	imp, _ = interceptDebugInfo(imp, nil, m)
-	m.applyByMethod(m.structDef, m.method, imp)
+	imp = m.interceptBarrier(imp)
+	// 提升方法和值接收体方法需要 mock 真实的方法体, 而不是编译器生成的包装函数
+	structTyp := reflect.TypeOf(m.structDef)
+	if implTyp, conv, rev := resolveMethod(structTyp, m.method); implTyp != structTyp &&
+		m.canApplyImpl(implTyp, rev, imp) {
+		m.applyByMethod(reflect.Zero(implTyp).Interface(), m.method, m.adaptImpl(implTyp, conv, rev, imp))
+	} else {
+		m.applyByMethod(m.structDef, m.method, imp)
+	}
 	logger.Consolefc(logger.DebugLevel, "mocker [%s] apply.", logger.Caller(6), m.String())
 }
 
@@ -332,6 +384,15 @@ func (m *MethodMocker) Origin(originFunc interface{}) ExportedMocker {
 	return m
 }
 
+// Block 为方法添加调用屏障, 每一次调用都停在 mock 回调中, 直到调用 Barrier.Release 或 Barrier.ReleaseAll 放行
+// 没有指定 Apply、Return 等时放行之后返回零值; 可以在 Block 之后继续指定返回值
+func (m *MethodMocker) Block() *Barrier {
+	if m.method == "" {
+		panic("method is empty")
+	}
+	return m.block(m.String(), true, reflect.TypeOf(m.methodIns), m.doApply)
+}
+
 // UnexportedMethodMocker 对结构体函数或方法进行 mock
 // 能支持到未导出类型、未导出类型的方法的 Mock
 type UnexportedMethodMocker struct {
@@ -361,7 +422,7 @@ func (m *UnexportedMethodMocker) objName() string {
 }
 
 // Method 设置结构体的方法名
-func (m *UnexportedMethodMocker) Method(name string) UnExportedMocker {
+func (m *UnexportedMethodMocker) Method(name string) InferredMocker {
 	m.methodName = name
 	return m
 }
@@ -379,6 +440,7 @@ func (m *UnexportedMethodMocker) Apply(callback interface{}) {
 		_, _ = unexports2.FindFuncByName(name)
 	}
 
+	checkLayout(m.typeName(), callback)
 	callback, _ = interceptDebugInfo(callback, nil, m)
 	m.applyByName(name, callback)
 	logger.Consolefc(logger.DebugLevel, "mocker [%s] apply.", logger.Caller(5), m.String())
@@ -405,6 +467,8 @@ func (m *UnexportedMethodMocker) As(aFunc interface{}) ExportedMocker {
 	if err != nil {
 		panic(err)
 	}
+	checkSignature(name, aFunc)
+	checkLayout(m.typeName(), aFunc)
 	newFunc := unexports2.NewFuncWithCodePtr(reflect.TypeOf(aFunc), originFuncPtr)
 	return &DefMocker{
 		baseMocker: m.baseMocker,
@@ -412,6 +476,32 @@ func (m *UnexportedMethodMocker) As(aFunc interface{}) ExportedMocker {
 	}
 }
 
+// When 指定条件匹配, 函数签名从调试信息中推导
+func (m *UnexportedMethodMocker) When(specArg ...interface{}) *When {
+	return m.inferred().When(specArg...)
+}
+
+// Return 指定返回值, 函数签名从调试信息中推导
+func (m *UnexportedMethodMocker) Return(value ...interface{}) *When {
+	return m.inferred().Return(value...)
+}
+
+// Returns 依次按顺序返回值, 函数签名从调试信息中推导
+func (m *UnexportedMethodMocker) Returns(values ...interface{}) *When {
+	return m.inferred().Returns(values...)
+}
+
+// inferred 根据调试信息推导的函数定义创建 DefMocker
+func (m *UnexportedMethodMocker) inferred() *DefMocker {
+	if m.methodName == "" {
+		panic("method name is empty")
+	}
+	return &DefMocker{
+		baseMocker: m.baseMocker,
+		funcDef:    inferFuncDef(m.objName()),
+	}
+}
+
 // UnexportedFuncMocker 对函数或方法进行 mock
 // 能支持到私有函数、私有类型的方法的 Mock
 type UnexportedFuncMocker struct {
@@ -461,6 +551,7 @@ func (m *UnexportedFuncMocker) As(funcDef interface{}) ExportedMocker {
 		panic(err)
 	}
 
+	checkSignature(m.objName(), funcDef)
 	newFunc := unexports2.NewFuncWithCodePtr(reflect.TypeOf(funcDef), originFuncPtr)
 	return &DefMocker{
 		baseMocker: m.baseMocker,
@@ -468,6 +559,29 @@ func (m *UnexportedFuncMocker) As(funcDef interface{}) ExportedMocker {
 	}
 }
 
+// When 指定条件匹配, 函数签名从调试信息中推导
+func (m *UnexportedFuncMocker) When(specArg ...interface{}) *When {
+	return m.inferred().When(specArg...)
+}
+
+// Return 指定返回值, 函数签名从调试信息中推导
+func (m *UnexportedFuncMocker) Return(value ...interface{}) *When {
+	return m.inferred().Return(value...)
+}
+
+// Returns 依次按顺序返回值, 函数签名从调试信息中推导
+func (m *UnexportedFuncMocker) Returns(values ...interface{}) *When {
+	return m.inferred().Returns(values...)
+}
+
+// inferred 根据调试信息推导的函数定义创建 DefMocker
+func (m *UnexportedFuncMocker) inferred() *DefMocker {
+	return &DefMocker{
+		baseMocker: m.baseMocker,
+		funcDef:    inferFuncDef(m.objName()),
+	}
+}
+
 // DefMocker 对函数或方法进行 mock，使用函数定义筛选
 type DefMocker struct {
 	*baseMocker
@@ -500,7 +614,9 @@ func (m *DefMocker) doApply(imp interface{}) {
 	}
 
 	funcName := functionName(m.funcDef)
+	m.rawImp = imp
 	imp, _ = interceptDebugInfo(imp, nil, m)
+	imp = m.interceptBarrier(imp)
 	if patch.IsGenericsFunc(funcName) {
 		// for generic variants func
 		m.applyByFunc(m.funcDef, imp)
@@ -577,3 +693,9 @@ func (m *DefMocker) Origin(originFunc interface{}) ExportedMocker {
 	m.origin = originFunc
 	return m
 }
+
+// Block 为函数添加调用屏障, 每一次调用都停在 mock 回调中, 直到调用 Barrier.Release 或 Barrier.ReleaseAll 放行
+// 没有指定 Apply、Return 等时放行之后返回零值; 可以在 Block 之后继续指定返回值, 比如: Return(nil)
+func (m *DefMocker) Block() *Barrier {
+	return m.block(m.String(), false, reflect.TypeOf(m.funcDef), m.doApply)
+}
@@ -4,13 +4,17 @@ package mocker_test
 
 import (
 	"errors"
+	"fmt"
 	"math/rand"
 	"os"
 	"testing"
 	"time"
+	"unsafe"
 
 	"github.com/stretchr/testify/suite"
 	mocker "github.com/tencent/goom"
+	"github.com/tencent/goom/erro"
+	"github.com/tencent/goom/internal/unexports2"
 	"github.com/tencent/goom/test"
 )
 
@@ -140,6 +144,170 @@ func (s *mockerTestSuite) TestUnitMethodReturn() {
 	})
 }
 
+// TestUnitMethodForInstance 测试只对指定实例的方法进行 mock
+func (s *mockerTestSuite) TestUnitMethodForInstance() {
+	s.Run("success", func() {
+		// 零长度结构体的实例地址可能相同, 所以使用非零长度的结构体
+		s1, s2, s3 := &test.MemStore{}, &test.MemStore{}, &test.MemStore{}
+
+		mock := mocker.Create()
+		mock.Struct(s1).ForInstance().Method("Get").Return("s1")
+		mock.Struct(s2).ForInstance().Method("Get").Apply(func(_ *test.MemStore, key string) string {
+			return "s2:" + key
+		})
+
+		s.Equal("s1", s1.Get("k"), "instance mock check")
+		s.Equal("s2:k", s2.Get("k"), "instance mock check")
+		s.Equal("mem:k", s3.Get("k"), "other instance origin check")
+
+		var origin func(*test.MemStore, string) string
+		mock.Struct(s1).ForInstance().Method("Get").Origin(&origin)
+		s.Equal("mem:k", origin(s1, "k"), "instance origin check")
+
+		mock.Struct(s1).ForInstance().Method("Get").Cancel()
+		s.Equal("mem:k", s1.Get("k"), "instance mock cancel check")
+		s.Equal("s2:k", s2.Get("k"), "other instance mock check")
+
+		mock.Reset()
+		s.Equal("mem:k", s2.Get("k"), "instance mock reset check")
+	})
+}
+
+// TestUnitMethodForInstanceTarget 测试再次调用 Struct 指定其它实例时, 不会改变之前返回的 Mocker 的实例
+func (s *mockerTestSuite) TestUnitMethodForInstanceTarget() {
+	s.Run("success", func() {
+		s1, s2 := &test.MemStore{}, &test.MemStore{}
+		mock := mocker.Create()
+		defer mock.Reset()
+
+		m := mock.Struct(s1)
+		mock.Struct(s2)
+		m.ForInstance().Method("Get").Return("s1")
+		s.Equal("s1", s1.Get("k"), "instance target check")
+		s.Equal("mem:k", s2.Get("k"), "other instance origin check")
+
+		s.Panics(func() {
+			m.ForInstance().Method("Get").Apply(func(_ *test.MemStore) string {
+				return ""
+			})
+		}, "callback signature check")
+	})
+}
+
+// TestUnitMethodForInstanceRepeat 测试反复 mock 和取消实例方法时复用跳板函数, 不会耗尽占位空间
+func (s *mockerTestSuite) TestUnitMethodForInstanceRepeat() {
+	s.Run("success", func() {
+		store := &test.MemStore{}
+		for i := 0; i < 100; i++ {
+			mock := mocker.Create()
+			mock.Struct(store).ForInstance().Method("Get").Return("fake")
+			s.Equal("fake", store.Get("k"), "instance mock check")
+			mock.Reset()
+			s.Equal("mem:k", store.Get("k"), "instance mock reset check")
+		}
+	})
+}
+
+// TestUnitPromotedMethod 测试嵌入结构体的提升方法 mock, 回调使用真实声明方法的接收体时所有调用路径都生效
+func (s *mockerTestSuite) TestUnitPromotedMethod() {
+	s.Run("success", func() {
+		mock := mocker.Create()
+		mock.Struct(&test.Outer{}).Method("Name").Apply(func(i *test.Inner) string {
+			return "fake"
+		})
+
+		o := &test.Outer{}
+		s.Equal("fake", o.Name(), "promoted method mock check")
+		s.Equal("fake", o.Inner.Name(), "promoted method mock check")
+		s.Equal("fake", (&test.Inner{}).Name(), "promoted method mock check")
+
+		mock.Reset()
+		s.Equal("inner", o.Name(), "promoted method mock reset check")
+	})
+}
+
+// TestUnitPromotedMethodOriginRepeat 测试反复 mock 提升方法并调用原方法时复用跳板函数
+func (s *mockerTestSuite) TestUnitPromotedMethodOriginRepeat() {
+	s.Run("success", func() {
+		for i := 0; i < 100; i++ {
+			mock := mocker.Create()
+			var origin func(*test.Outer) string
+			mock.Struct(&test.Outer{}).Method("Name").Origin(&origin).Apply(func(*test.Inner) string {
+				return "fake-" + origin(&test.Outer{})
+			})
+			s.Equal("fake-inner", (&test.Outer{}).Name(), "promoted method origin check")
+			mock.Reset()
+		}
+	})
+}
+
+// TestUnitPromotedMethodOuterReceiver 测试回调使用外层结构体接收体时只 mock 包装函数, 不会改写其它调用方的接收体
+func (s *mockerTestSuite) TestUnitPromotedMethodOuterReceiver() {
+	s.Run("success", func() {
+		mock := mocker.Create()
+		defer mock.Reset()
+		mock.Struct(&test.OffsetOuter{}).Method("Name").Apply(func(o *test.OffsetOuter) string {
+			return fmt.Sprintf("fake-%d", o.X)
+		})
+
+		s.Equal("fake-7", nameOf(&test.OffsetOuter{X: 7}), "wrapper mock check")
+		s.Equal("inner", (&test.Inner{}).Name(), "standalone receiver check")
+		s.Equal("inner", (&test.Outer{}).Name(), "other outer receiver check")
+	})
+}
+
+// nameOf 通过接口调用 Name 方法
+func nameOf(named interface{ Name() string }) string {
+	return named.Name()
+}
+
+// TestUnitValueReceiverMethod 测试通过指针指定的值接收体方法 mock, 所有调用路径都生效
+func (s *mockerTestSuite) TestUnitValueReceiverMethod() {
+	s.Run("success", func() {
+		mock := mocker.Create()
+		mock.Struct(&test.Inner{}).Method("Value").Return(5)
+
+		in := test.Inner{}
+		s.Equal(5, in.Value(), "value receiver method mock check")
+		s.Equal(5, (&in).Value(), "value receiver method mock check")
+		s.Equal(5, (&test.Outer{}).Value(), "value receiver method mock check")
+
+		mock.Reset()
+		s.Equal(0, in.Value(), "value receiver method mock reset check")
+	})
+}
+
+// delegateFake 用于测试结构体方法委托的 fake 类型
+type delegateFake struct {
+	factor int
+}
+
+// Call 和 test.Fake.Call 签名一致
+func (f *delegateFake) Call(i int) int {
+	return i * f.factor
+}
+
+// Call2 和 test.Fake.Call2 签名不一致
+func (f *delegateFake) Call2(i int) string {
+	return "fake"
+}
+
+// TestUnitMethodDelegateTo 测试结构体的所有方法委托给 fake 对象
+func (s *mockerTestSuite) TestUnitMethodDelegateTo() {
+	s.Run("success", func() {
+		mock := mocker.Create()
+		unmatched := mock.Struct(&test.Fake{}).DelegateTo(&delegateFake{factor: 10})
+		s.Equal([]string{"Call2", "Invokecall"}, unmatched, "unmatched methods check")
+
+		f := &test.Fake{}
+		s.Equal(10, f.Call(1), "delegate mock check")
+		s.Equal(1, f.Call2(1), "unmatched method check")
+
+		mock.Reset()
+		s.Equal(1, f.Call(1), "delegate mock reset check")
+	})
+}
+
 // TestUnitUnExportedMethodApply 测试结构体的未导出方法 mock apply
 func (s *mockerTestSuite) TestUnitUnExportedMethodApply() {
 	s.Run("success", func() {
@@ -172,6 +340,80 @@ func (s *mockerTestSuite) TestUnitUnexportedMethodReturn() {
 	})
 }
 
+// TestUnitUnexportedInferSignature 测试未导出函数(或方法)不调用 As, 从调试信息中推导签名
+func (s *mockerTestSuite) TestUnitUnexportedInferSignature() {
+	skipWithoutDWARF(s.T())
+	s.Run("func", func() {
+		mock := mocker.Create()
+		mock.Pkg("github.com/tencent/goom/test").ExportFunc("foo").Return(3).When(2).Return(4)
+		s.Equal(4, test.Invokefoo(2), "foo mock check")
+		s.Equal(3, test.Invokefoo(1), "foo mock default return check")
+
+		mock.Reset()
+		s.Equal(2, test.Invokefoo(2), "foo mock reset check")
+	})
+	s.Run("method", func() {
+		mock := mocker.Create()
+		mock.Struct(&test.Fake{}).ExportMethod("call").Return(6)
+
+		f := &test.Fake{}
+		s.Equal(6, f.Invokecall(1), "call mock check")
+
+		mock.Reset()
+		s.Equal(1, f.Invokecall(1), "call mock reset check")
+	})
+}
+
+// TestUnitUnexportedSignatureNotMatch 测试 As 模板函数签名和调试信息不一致
+func (s *mockerTestSuite) TestUnitUnexportedSignatureNotMatch() {
+	skipWithoutDWARF(s.T())
+	s.Run("args length", func() {
+		var expectErr error
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					expectErr, _ = err.(error)
+				}
+			}()
+
+			mock := mocker.Create()
+			defer mock.Reset()
+			mock.Pkg("github.com/tencent/goom/test").ExportFunc("foo").As(func(i, j int) int {
+				return 0
+			})
+		}()
+
+		s.IsType(&erro.SignatureNotMatch{}, expectErr, "signature not match check")
+		s.Contains(expectErr.Error(), "args length: 2, expect: 1", "signature not match reason check")
+	})
+	s.Run("arg type", func() {
+		var expectErr error
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					expectErr, _ = err.(error)
+				}
+			}()
+
+			mock := mocker.Create()
+			defer mock.Reset()
+			mock.Struct(&test.Fake{}).ExportMethod("call").As(func(_ *test.Fake, i string) int {
+				return 0
+			})
+		}()
+
+		s.IsType(&erro.SignatureNotMatch{}, expectErr, "signature not match check")
+		s.Contains(expectErr.Error(), "arg[1] type: string, expect: int", "signature not match reason check")
+	})
+}
+
+// skipWithoutDWARF 测试二进制没有调试信息时跳过, 需要加上构建参数: -ldflags="-s=false -w=false"
+func skipWithoutDWARF(t *testing.T) {
+	if _, err := unexports2.FuncSignature("github.com/tencent/goom/test.foo"); err != nil {
+		t.Skip("dwarf is not available: ", err)
+	}
+}
+
 // TestUnitUnExportStruct 测试未导出结构体的方法 mock apply
 func (s *mockerTestSuite) TestUnitUnExportStruct() {
 	s.Run("success", func() {
@@ -200,6 +442,51 @@ func (s *mockerTestSuite) TestUnitUnExportStruct() {
 	})
 }
 
+// TestUnitUnExportStructLayout 测试未导出结构体的内存布局: 字段访问、源码生成和布局校验
+func (s *mockerTestSuite) TestUnitUnExportStructLayout() {
+	s.Run("field", func() {
+		mock := mocker.Create()
+		st := mock.Pkg("github.com/tencent/goom/test").ExportStruct("*fake")
+		st.Method("call").Apply(func(self unsafe.Pointer, i int) int {
+			return i + st.Ctx(self).Field("field2").Interface().(int)
+		})
+
+		f := test.NewUnexportedFake()
+		s.Equal(3, f.Invokecall(1), "call mock field check")
+
+		mock.Reset()
+		s.Equal(1, f.Invokecall(1), "call mock reset check")
+	})
+	s.Run("fake source", func() {
+		mock := mocker.Create()
+		src := mock.Pkg("github.com/tencent/goom/test").ExportStruct("*fake").FakeSource("_fake")
+		s.Contains(src, "type _fake struct {\n\tfield1 string\n\tfield2 int\n}", "fake source check")
+	})
+	s.Run("layout not match", func() {
+		var expectErr error
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					expectErr, _ = err.(error)
+				}
+			}()
+
+			type _fake struct {
+				_ int
+				_ string
+			}
+			mock := mocker.Create()
+			defer mock.Reset()
+			mock.Pkg("github.com/tencent/goom/test").ExportStruct("*fake").
+				Method("call").Apply(func(_ *_fake, i int) int {
+				return i * 2
+			})
+		}()
+
+		s.IsType(&erro.LayoutNotMatch{}, expectErr, "layout not match check")
+	})
+}
+
 // TestMultiReturn 测试调用原函数多返回
 func (s *mockerTestSuite) TestMultiReturn() {
 	s.Run("success", func() {
@@ -0,0 +1,202 @@
+// Package mocker 定义了 mock 的外层用户使用 API 定义,
+// 包括函数、方法、接口、未导出函数(或方法的)的 Mocker 的实现。
+// 当前文件实现了网络连接的重定向: 通过 mock (*net.Dialer).DialContext,
+// 将 net.Dial、net.DialTimeout、http.DefaultTransport 等发起的连接路由到进程内的处理函数或者本地服务,
+// 没有路由的目标地址直接返回错误, 不会访问真实的网络。
+package mocker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tencent/goom/internal/logger"
+)
+
+// netCacheKey 网络连接 mock 在 Builder 中的缓存 key
+const netCacheKey = "net"
+
+// NetMocker 网络连接 mock
+type NetMocker struct {
+	lock sync.RWMutex
+	// routes 目标地址(host:port)到建立连接函数的映射
+	routes   map[string]func(ctx context.Context) (net.Conn, error)
+	mockers  []Mocker
+	canceled bool
+
+	// dialContext 调用原方法的跳板函数
+	dialContext func(d *net.Dialer, ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// Net 创建网络连接 mock, 同一个 builder 多次调用返回同一个路由表
+// mock 之后所有通过 net.Dialer 建立的连接都只能访问 Route、Handle 指定的地址
+func Net(b *Builder) *NetMocker {
+	if mocker, ok := b.mockers[netCacheKey]; ok && !mocker.Canceled() {
+		return mocker.(*NetMocker)
+	}
+
+	m := &NetMocker{
+		routes:      make(map[string]func(ctx context.Context) (net.Conn, error)),
+		dialContext: dialContextTrampoline,
+	}
+	dial := b.Struct(&net.Dialer{}).Method("DialContext")
+	dial.Origin(&m.dialContext).Apply(m.doDialContext)
+	m.mockers = []Mocker{dial}
+
+	b.cache(netCacheKey, m)
+	logger.Consolefc(logger.DebugLevel, "mocker [%s] apply.", logger.Caller(5), m.String())
+	return m
+}
+
+// Route 将到 addr 的连接路由到 listener
+// listener 为 PipeListener 时通过 net.Pipe 建立内存连接; 否则连接 listener 的本地地址, 比如 httptest.Server 的 Listener
+func (m *NetMocker) Route(addr string, listener net.Listener) *NetMocker {
+	if pipe, ok := listener.(*PipeListener); ok {
+		return m.route(addr, pipe.dial)
+	}
+	return m.route(addr, func(ctx context.Context) (net.Conn, error) {
+		local := listener.Addr()
+		return m.dialContext(&net.Dialer{}, ctx, local.Network(), local.String())
+	})
+}
+
+// Handle 将到 addr 的连接路由到处理函数, 每个连接通过 net.Pipe 建立, handler 在新的协程中处理服务端的连接
+func (m *NetMocker) Handle(addr string, handler func(conn net.Conn)) *NetMocker {
+	return m.route(addr, func(context.Context) (net.Conn, error) {
+		server, client := net.Pipe()
+		go handler(server)
+		return client, nil
+	})
+}
+
+// Apply 不支持, 请使用 Route 和 Handle
+func (m *NetMocker) Apply(interface{}) {
+	panic("NetMocker does not support Apply, use Route or Handle instead.")
+}
+
+// Cancel 取消 mock, 恢复访问真实的网络
+func (m *NetMocker) Cancel() {
+	for _, mocker := range m.mockers {
+		mocker.Cancel()
+	}
+	m.canceled = true
+}
+
+// Canceled 是否取消了 mock
+func (m *NetMocker) Canceled() bool {
+	return m.canceled
+}
+
+// String mock 的名称或描述, 方便调试和问题排查
+func (m *NetMocker) String() string {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	addrs := make([]string, 0, len(m.routes))
+	for addr := range m.routes {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	return fmt.Sprintf("net[%s]", strings.Join(addrs, ","))
+}
+
+func (m *NetMocker) route(addr string, dial func(ctx context.Context) (net.Conn, error)) *NetMocker {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.routes[addr] = dial
+	return m
+}
+
+func (m *NetMocker) doDialContext(_ *net.Dialer, ctx context.Context, network, address string) (net.Conn, error) {
+	m.lock.RLock()
+	dial, ok := m.routes[address]
+	m.lock.RUnlock()
+	if !ok {
+		return nil, &net.OpError{Op: "dial", Net: network,
+			Err: fmt.Errorf("goom: no route to %s, add it with mocker.Net(builder).Route or Handle", address)}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+	conn, err := dial(ctx)
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+	return conn, nil
+}
+
+// PipeListener 通过 net.Pipe 建立内存连接的 net.Listener, 可以作为 http.Serve、httptest.Server 等的 Listener
+type PipeListener struct {
+	addr  pipeAddr
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+// NewPipeListener 创建内存连接的 net.Listener, addr 为 Addr() 返回的地址
+func NewPipeListener(addr string) *PipeListener {
+	return &PipeListener{
+		addr:  pipeAddr(addr),
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+}
+
+// Accept 等待并返回下一个连接
+func (l *PipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close 关闭 Listener, 阻塞中的 Accept 和 dial 返回错误
+func (l *PipeListener) Close() error {
+	l.once.Do(func() {
+		close(l.done)
+	})
+	return nil
+}
+
+// Addr 返回 Listener 的地址
+func (l *PipeListener) Addr() net.Addr {
+	return l.addr
+}
+
+// dial 建立内存连接, 等待服务端 Accept
+func (l *PipeListener) dial(ctx context.Context) (net.Conn, error) {
+	server, client := net.Pipe()
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.done:
+		_, _ = server.Close(), client.Close()
+		return nil, net.ErrClosed
+	case <-ctx.Done():
+		_, _ = server.Close(), client.Close()
+		return nil, ctx.Err()
+	}
+}
+
+// pipeAddr 内存连接的地址
+type pipeAddr string
+
+// Network 网络类型
+func (a pipeAddr) Network() string {
+	return "pipe"
+}
+
+// String 地址
+func (a pipeAddr) String() string {
+	return string(a)
+}
+
+// dialContextTrampoline 调用原方法的跳板函数占位, mock 之后函数体会被替换, 实际不会执行该函数体, 但是必须编写
+func dialContextTrampoline(d *net.Dialer, ctx context.Context, network, address string) (net.Conn, error) {
+	fmt.Println("only for placeholder, will not call", d, ctx, network, address)
+	return nil, nil
+}
@@ -0,0 +1,90 @@
+package mocker_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/tencent/goom"
+)
+
+// TestUnitNetTestSuite 测试入口
+func TestUnitNetTestSuite(t *testing.T) {
+	suite.Run(t, new(netTestSuite))
+}
+
+type netTestSuite struct {
+	suite.Suite
+}
+
+// TestUnitNetHandle 测试将连接路由到处理函数
+func (s *netTestSuite) TestUnitNetHandle() {
+	mock := mocker.Create()
+	defer mock.Reset()
+	mocker.Net(mock).Handle("redis:6379", func(conn net.Conn) {
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		_, _ = fmt.Fprintf(conn, "echo %s", line)
+	})
+
+	conn, err := net.DialTimeout("tcp", "redis:6379", time.Second)
+	s.Require().NoError(err, "dial check")
+	defer conn.Close()
+	_, err = fmt.Fprintf(conn, "PING\n")
+	s.NoError(err, "write check")
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	s.NoError(err, "read check")
+	s.Equal("echo PING\n", line, "read check")
+}
+
+// TestUnitNetRoute 测试将连接路由到本地服务和内存 Listener
+func (s *netTestSuite) TestUnitNetRoute() {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "hello %s", r.Host)
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	pipe := mocker.NewPipeListener("pipe:80")
+	defer pipe.Close()
+	go func() {
+		_ = http.Serve(pipe, handler)
+	}()
+
+	mock := mocker.Create()
+	defer mock.Reset()
+	mocker.Net(mock).Route("api.example.com:80", server.Listener).Route("pipe.example.com:80", pipe)
+
+	client := &http.Client{Transport: &http.Transport{DialContext: (&net.Dialer{}).DialContext}}
+	defer client.CloseIdleConnections()
+	for _, host := range []string{"api.example.com", "pipe.example.com"} {
+		resp, err := client.Get("http://" + host + "/")
+		s.Require().NoError(err, "get check")
+		body, err := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		s.NoError(err, "read check")
+		s.Equal("hello "+host, string(body), "body check")
+	}
+}
+
+// TestUnitNetNoRoute 测试没有路由的地址返回错误
+func (s *netTestSuite) TestUnitNetNoRoute() {
+	mock := mocker.Create()
+	defer mock.Reset()
+	mocker.Net(mock)
+
+	_, err := net.Dial("tcp", "db.example.com:3306")
+	s.Error(err, "no route check")
+	s.Contains(err.Error(), "no route to db.example.com:3306", "no route check")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = (&net.Dialer{}).DialContext(ctx, "tcp", "db.example.com:3306")
+	s.Error(err, "dialer no route check")
+}
@@ -0,0 +1,167 @@
+// Package mocker 定义了 mock 的外层用户使用 API 定义,
+// 包括函数、方法、接口、未导出函数(或方法的)的 Mocker 的实现。
+// 当前文件实现了方法真实实现的解析:
+// 对于嵌入结构体的提升方法、通过指针调用的值接收体方法, 编译器会生成包装函数,
+// 需要找到真正声明方法的接收体类型, 对其方法体进行 mock, 才能拦截所有的调用路径;
+// 提升方法的回调需要使用真正声明方法的接收体类型, 使用外层结构体类型时只 mock 包装函数。
+package mocker
+
+import (
+	"reflect"
+	"runtime"
+	"unsafe"
+
+	"github.com/tencent/goom/erro"
+	"github.com/tencent/goom/internal/proxy"
+)
+
+// autogeneratedFile 编译器生成的包装函数的文件名
+const autogeneratedFile = "<autogenerated>"
+
+// receiverConv 接收体转换函数
+type receiverConv func(v reflect.Value) reflect.Value
+
+// resolveMethod 解析方法的真实实现, 返回真实声明方法的接收体类型和接收体转换函数;
+// conv 将 Struct(...) 指定类型的接收体转换为真实声明方法的接收体,
+// rev 为其逆转换, 无法还原时为 nil: 真实的方法体被所有调用方共享, 嵌入字段的接收体可能来自独立的变量或者其它外层结构体,
+// 不能通过字段偏移量还原外层结构体, 所以嵌入结构体的提升方法都无法还原
+func resolveMethod(typ reflect.Type, name string) (implTyp reflect.Type, conv, rev receiverConv) {
+	identity := func(v reflect.Value) reflect.Value { return v }
+	method, ok := typ.MethodByName(name)
+	if !ok || !isAutogenerated(method.Func.Pointer()) {
+		return typ, identity, identity
+	}
+
+	// 通过指针调用值接收体方法的包装函数: (*T).M -> T.M
+	if typ.Kind() == reflect.Ptr {
+		if _, ok := typ.Elem().MethodByName(name); ok {
+			implTyp, next, nextRev := resolveMethod(typ.Elem(), name)
+			conv = func(v reflect.Value) reflect.Value {
+				return next(v.Elem())
+			}
+			if nextRev != nil {
+				rev = func(v reflect.Value) reflect.Value {
+					p := reflect.New(typ.Elem())
+					p.Elem().Set(nextRev(v))
+					return p
+				}
+			}
+			return implTyp, conv, rev
+		}
+	}
+
+	// 嵌入结构体的提升方法的包装函数: (*Outer).M -> (*Inner).M
+	base := typ
+	if base.Kind() == reflect.Ptr {
+		base = base.Elem()
+	}
+	if base.Kind() != reflect.Struct {
+		return typ, identity, identity
+	}
+	for i := 0; i < base.NumField(); i++ {
+		field := base.Field(i)
+		if !field.Anonymous {
+			continue
+		}
+		fieldTyp, addr := embeddedMethodOwner(field.Type, name, typ.Kind() == reflect.Ptr)
+		if fieldTyp == nil {
+			continue
+		}
+		implTyp, next, _ := resolveMethod(fieldTyp, name)
+		index := i
+		conv = func(v reflect.Value) reflect.Value {
+			if v.Kind() == reflect.Ptr {
+				v = v.Elem()
+			}
+			f := v.Field(index)
+			if f.CanAddr() {
+				// 去除未导出字段的只读标记
+				f = reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem()
+			}
+			if addr {
+				f = f.Addr()
+			}
+			return next(f)
+		}
+		return implTyp, conv, nil
+	}
+	return typ, identity, identity
+}
+
+// embeddedMethodOwner 判断嵌入字段是否提供了方法, 返回提供方法的类型以及是否需要对字段取地址
+func embeddedMethodOwner(fieldTyp reflect.Type, name string, addressable bool) (reflect.Type, bool) {
+	if _, ok := fieldTyp.MethodByName(name); ok {
+		return fieldTyp, false
+	}
+	if fieldTyp.Kind() != reflect.Ptr && addressable {
+		if _, ok := reflect.PtrTo(fieldTyp).MethodByName(name); ok {
+			return reflect.PtrTo(fieldTyp), true
+		}
+	}
+	return nil, false
+}
+
+// isAutogenerated 判断函数是否为编译器生成的包装函数
+func isAutogenerated(pc uintptr) bool {
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return false
+	}
+	file, _ := fn.FileLine(fn.Entry())
+	return file == autogeneratedFile
+}
+
+// canApplyImpl 判断能否 mock 真实的方法体:
+// 回调的第一个参数是真实声明方法的接收体类型、接收体可以还原, 或者通过 When、Return 指定返回值(不使用接收体)时可以;
+// 否则 Apply 的回调需要 Struct(...) 指定类型的接收体, 只能 mock 编译器生成的包装函数,
+// 通过嵌入字段直接调用(比如 outer.Inner.M() 以及被编译为该形式的 outer.M())的路径不会被拦截
+func (m *MethodMocker) canApplyImpl(implTyp reflect.Type, rev receiverConv, imp interface{}) bool {
+	return rev != nil || m.when != nil || reflect.TypeOf(imp).In(0) == implTyp
+}
+
+// adaptImpl 将 Struct(...) 指定类型的方法回调适配为真实声明方法的接收体类型的回调
+// 回调的第一个参数可以是真实声明方法的接收体类型, 也可以是 Struct(...) 指定的类型;
+// 后者在无法还原接收体时(仅限 When、Return 等不使用接收体的回调)会传入接收体类型的零值
+func (m *MethodMocker) adaptImpl(implTyp reflect.Type, conv, rev receiverConv, imp interface{}) interface{} {
+	implMethod, _ := implTyp.MethodByName(m.method)
+	if m.origin != nil && reflect.TypeOf(m.origin).Elem() != implMethod.Type {
+		m.origin = m.adaptOrigin(implMethod, conv)
+	}
+	impV := reflect.ValueOf(imp)
+	if impV.Type().In(0) == implTyp {
+		return imp
+	}
+
+	structTyp := reflect.TypeOf(m.structDef)
+	return reflect.MakeFunc(implMethod.Type, func(args []reflect.Value) []reflect.Value {
+		receiver := reflect.Zero(structTyp)
+		if rev != nil {
+			receiver = rev(args[0])
+		}
+		args = append([]reflect.Value{receiver}, args[1:]...)
+		if impV.Type().IsVariadic() {
+			return impV.CallSlice(args)
+		}
+		return impV.Call(args)
+	}).Interface()
+}
+
+// adaptOrigin 为真实声明方法的接收体类型动态生成跳板函数, 并将用户指定的原函数适配到该跳板函数
+func (m *MethodMocker) adaptOrigin(implMethod reflect.Method, conv receiverConv) interface{} {
+	implFuncTyp := implMethod.Type
+	// 同一个方法反复 mock 时复用同一个跳板函数
+	trampoline, err := proxy.Trampoline(implMethod.Func.Pointer(), implFuncTyp)
+	if err != nil {
+		panic(erro.NewTraceableErrorc("create trampoline of "+m.String()+" error", err))
+	}
+	originV := reflect.ValueOf(m.origin).Elem()
+	originV.Set(reflect.MakeFunc(originV.Type(), func(args []reflect.Value) []reflect.Value {
+		args = append([]reflect.Value{conv(args[0])}, args[1:]...)
+		fn := reflect.ValueOf(trampoline).Elem()
+		if implFuncTyp.IsVariadic() {
+			return fn.CallSlice(args)
+		}
+		return fn.Call(args)
+	}))
+	return trampoline
+}
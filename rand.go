@@ -0,0 +1,198 @@
+// Package mocker 定义了 mock 的外层用户使用 API 定义,
+// 包括函数、方法、接口、未导出函数(或方法的)的 Mocker 的实现。
+// 当前文件实现了随机数的 mock: 将 math/rand、math/rand/v2 的顶层函数和 crypto/rand.Read 替换为固定种子的随机数源,
+// 使 UUID、随机退避、洗牌等依赖随机数的逻辑在测试中可以复现。
+package mocker
+
+import (
+	cryptorand "crypto/rand"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tencent/goom/erro"
+	"github.com/tencent/goom/internal/logger"
+)
+
+// randCacheKey 随机数 mock 在 Builder 中的缓存 key
+const randCacheKey = "rand"
+
+// randFunc 被 mock 的随机数函数, 调用随机数源的同名方法
+type randFunc struct {
+	fn     interface{}
+	method string
+}
+
+// mathRandFuncs math/rand 中被 mock 的顶层函数
+var mathRandFuncs = []randFunc{
+	{rand.Seed, "Seed"},
+	{rand.Int, "Int"},
+	{rand.Intn, "Intn"},
+	{rand.Int31, "Int31"},
+	{rand.Int31n, "Int31n"},
+	{rand.Int63, "Int63"},
+	{rand.Int63n, "Int63n"},
+	{rand.Uint32, "Uint32"},
+	{rand.Uint64, "Uint64"},
+	{rand.Float32, "Float32"},
+	{rand.Float64, "Float64"},
+	{rand.NormFloat64, "NormFloat64"},
+	{rand.ExpFloat64, "ExpFloat64"},
+	{rand.Perm, "Perm"},
+	{rand.Shuffle, "Shuffle"},
+	{rand.Read, "Read"},
+}
+
+// patchRandV2 mock math/rand/v2 的顶层函数, go1.22 以下的版本为 nil
+var patchRandV2 func(m *RandMocker, b *Builder, seed int64)
+
+// RandMocker 随机数 mock
+type RandMocker struct {
+	// lock 随机数源和返回值序列不是并发安全的, 调用时需要加锁
+	lock sync.Mutex
+	// sources 包名到固定种子的随机数源的映射
+	sources map[string]reflect.Value
+	// newSources 包名到创建随机数源函数的映射, 用于重置种子
+	newSources map[string]func(seed int64) interface{}
+	// scripts 函数地址到指定返回值序列的映射
+	scripts map[uintptr]*When
+	// funcs 函数地址到函数定义的映射
+	funcs    map[uintptr]interface{}
+	mockers  []Mocker
+	canceled bool
+}
+
+// Rand 使用固定的种子 mock math/rand、math/rand/v2 的顶层函数和 crypto/rand.Read、crypto/rand.Reader
+// 同一个 builder 多次调用时使用新的种子重置随机数源; 可以通过 Func 为单个函数指定返回值序列
+// 注意: rand.Shuffle 的 swap 回调在锁内执行, 不能在 swap 中调用随机数函数
+func Rand(b *Builder, seed int64) *RandMocker {
+	if mocker, ok := b.mockers[randCacheKey]; ok && !mocker.Canceled() {
+		m := mocker.(*RandMocker)
+		m.Seed(seed)
+		return m
+	}
+
+	m := &RandMocker{
+		sources:    make(map[string]reflect.Value),
+		newSources: make(map[string]func(seed int64) interface{}),
+		scripts:    make(map[uintptr]*When),
+		funcs:      make(map[uintptr]interface{}),
+	}
+	m.patch(b, "math/rand", seed, func(seed int64) interface{} {
+		return rand.New(rand.NewSource(seed))
+	}, mathRandFuncs)
+	// crypto/rand 使用独立的随机数源, 避免影响 math/rand 的序列
+	m.patch(b, "crypto/rand", seed, func(seed int64) interface{} {
+		return rand.New(rand.NewSource(seed))
+	}, []randFunc{{cryptorand.Read, "Read"}})
+	reader := b.Var(&cryptorand.Reader)
+	reader.Set(randReader{})
+	m.mockers = append(m.mockers, reader)
+	if patchRandV2 != nil {
+		patchRandV2(m, b, seed)
+	}
+
+	b.cache(randCacheKey, m)
+	logger.Consolefc(logger.DebugLevel, "mocker [%s] apply.", logger.Caller(5), m.String())
+	return m
+}
+
+// Seed 使用新的种子重置所有的随机数源
+func (m *RandMocker) Seed(seed int64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for pkg, newSource := range m.newSources {
+		m.sources[pkg] = reflect.ValueOf(newSource(seed))
+	}
+}
+
+// Func 为随机数函数指定返回值, 比如: Func(rand.Intn).Returns(3, 1, 4), 指定的返回值优先于随机数源
+// fn 必须是 Rand mock 的函数, 比如: rand.Intn、crypto/rand.Read
+func (m *RandMocker) Func(fn interface{}) *When {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		panic(erro.NewIllegalParamTypeError("fn", fmt.Sprintf("%T", fn), "func"))
+	}
+	key := v.Pointer()
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, ok := m.funcs[key]; !ok {
+		panic(erro.NewIllegalParamError("fn", runtime.FuncForPC(key).Name()+" is not mocked by Rand"))
+	}
+	if when, ok := m.scripts[key]; ok {
+		return when
+	}
+	when, err := CreateWhen(nil, fn, nil, nil, false)
+	if err != nil {
+		panic(err)
+	}
+	m.scripts[key] = when
+	return when
+}
+
+// Apply 不支持, 请使用 Func 指定返回值
+func (m *RandMocker) Apply(interface{}) {
+	panic("RandMocker does not support Apply, use Func(fn).Returns(...) instead.")
+}
+
+// Cancel 取消 mock, 恢复真实的随机数
+func (m *RandMocker) Cancel() {
+	for _, mocker := range m.mockers {
+		mocker.Cancel()
+	}
+	m.canceled = true
+}
+
+// Canceled 是否取消了 mock
+func (m *RandMocker) Canceled() bool {
+	return m.canceled
+}
+
+// String mock 的名称或描述, 方便调试和问题排查
+func (m *RandMocker) String() string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	pkgs := make([]string, 0, len(m.sources))
+	for pkg := range m.sources {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	return fmt.Sprintf("rand[%s]", strings.Join(pkgs, ","))
+}
+
+// patch 创建 pkg 的随机数源, 并将 funcs 替换为调用随机数源的同名方法
+func (m *RandMocker) patch(b *Builder, pkg string, seed int64, newSource func(seed int64) interface{},
+	funcs []randFunc) {
+	m.newSources[pkg] = newSource
+	m.sources[pkg] = reflect.ValueOf(newSource(seed))
+	for _, f := range funcs {
+		key := reflect.ValueOf(f.fn).Pointer()
+		method := f.method
+		imp := reflect.MakeFunc(reflect.TypeOf(f.fn), func(args []reflect.Value) []reflect.Value {
+			m.lock.Lock()
+			defer m.lock.Unlock()
+			if when, ok := m.scripts[key]; ok {
+				if results, ok := when.tryInvoke(args); ok {
+					return results
+				}
+			}
+			return m.sources[pkg].MethodByName(method).Call(args)
+		})
+		mocker := b.Func(f.fn)
+		mocker.Apply(imp.Interface())
+		m.funcs[key] = f.fn
+		m.mockers = append(m.mockers, mocker)
+	}
+}
+
+// randReader 替换 crypto/rand.Reader, 读取 mock 之后的 crypto/rand.Read
+type randReader struct{}
+
+// Read 读取随机字节
+func (randReader) Read(p []byte) (int, error) {
+	return cryptorand.Read(p)
+}
@@ -0,0 +1,66 @@
+package mocker_test
+
+import (
+	cryptorand "crypto/rand"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/tencent/goom"
+)
+
+// TestUnitRandTestSuite 测试入口
+func TestUnitRandTestSuite(t *testing.T) {
+	suite.Run(t, new(randTestSuite))
+}
+
+type randTestSuite struct {
+	suite.Suite
+}
+
+// sample 依次调用随机数函数, 用于对比两次的结果
+func sample() []interface{} {
+	buf := make([]byte, 8)
+	_, _ = cryptorand.Read(buf)
+	reader := make([]byte, 8)
+	_, _ = io.ReadFull(cryptorand.Reader, reader)
+	return []interface{}{rand.Int(), rand.Intn(100), rand.Float64(), rand.Perm(5), buf, reader}
+}
+
+// TestUnitRand 测试固定种子的随机数可以复现
+func (s *randTestSuite) TestUnitRand() {
+	mock := mocker.Create()
+	mocker.Rand(mock, 42)
+	first := sample()
+
+	mocker.Rand(mock, 42)
+	s.Equal(first, sample(), "same seed check")
+
+	mocker.Rand(mock, 43)
+	s.NotEqual(first, sample(), "different seed check")
+
+	mock.Reset()
+	s.NotEqual(first, sample(), "reset check")
+}
+
+// TestUnitRandFunc 测试为随机数函数指定返回值序列
+func (s *randTestSuite) TestUnitRandFunc() {
+	mock := mocker.Create()
+	defer mock.Reset()
+	r := mocker.Rand(mock, 1)
+	r.Func(rand.Intn).Returns(3, 1, 4)
+	r.Func(rand.Int63n).When(int64(10)).Return(int64(7))
+
+	s.Equal(3, rand.Intn(10), "returns check")
+	s.Equal(1, rand.Intn(10), "returns check")
+	s.Equal(4, rand.Intn(10), "returns check")
+	s.Equal(4, rand.Intn(10), "returns last check")
+
+	s.Equal(int64(7), rand.Int63n(10), "when check")
+	s.Less(rand.Int63n(5), int64(5), "fallback to source check")
+
+	s.Panics(func() {
+		r.Func(rand.NewSource)
+	}, "not mocked check")
+}
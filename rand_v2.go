@@ -0,0 +1,37 @@
+//go:build go1.22
+// +build go1.22
+
+package mocker
+
+import (
+	"math/rand/v2"
+)
+
+// randV2Funcs math/rand/v2 中被 mock 的顶层函数, 泛型函数 rand.N 不支持
+var randV2Funcs = []randFunc{
+	{rand.Int, "Int"},
+	{rand.IntN, "IntN"},
+	{rand.Int32, "Int32"},
+	{rand.Int32N, "Int32N"},
+	{rand.Int64, "Int64"},
+	{rand.Int64N, "Int64N"},
+	{rand.Uint32, "Uint32"},
+	{rand.Uint32N, "Uint32N"},
+	{rand.Uint64, "Uint64"},
+	{rand.Uint64N, "Uint64N"},
+	{rand.UintN, "UintN"},
+	{rand.Float32, "Float32"},
+	{rand.Float64, "Float64"},
+	{rand.NormFloat64, "NormFloat64"},
+	{rand.ExpFloat64, "ExpFloat64"},
+	{rand.Perm, "Perm"},
+	{rand.Shuffle, "Shuffle"},
+}
+
+func init() {
+	patchRandV2 = func(m *RandMocker, b *Builder, seed int64) {
+		m.patch(b, "math/rand/v2", seed, func(seed int64) interface{} {
+			return rand.New(rand.NewPCG(uint64(seed), uint64(seed)))
+		}, randV2Funcs)
+	}
+}
@@ -0,0 +1,24 @@
+//go:build go1.22
+// +build go1.22
+
+package mocker_test
+
+import (
+	"math/rand/v2"
+
+	"github.com/tencent/goom"
+)
+
+// TestUnitRandV2 测试 math/rand/v2 固定种子的随机数可以复现
+func (s *randTestSuite) TestUnitRandV2() {
+	mock := mocker.Create()
+	defer mock.Reset()
+	mocker.Rand(mock, 42)
+	first := []interface{}{rand.Int(), rand.IntN(100), rand.Uint64(), rand.Perm(5)}
+
+	mocker.Rand(mock, 42)
+	s.Equal(first, []interface{}{rand.Int(), rand.IntN(100), rand.Uint64(), rand.Perm(5)}, "same seed check")
+
+	mocker.Rand(mock, 42).Func(rand.IntN).Returns(9)
+	s.Equal(9, rand.IntN(100), "returns check")
+}
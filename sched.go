@@ -0,0 +1,295 @@
+// Package mocker 定义了 mock 的外层用户使用 API 定义,
+// 包括函数、方法、接口、未导出函数(或方法的)的 Mocker 的实现。
+// 当前文件实现了异步任务的同步调度: mock 提交异步任务的函数或方法(比如 pool.Submit、(*errgroup.Group).Go),
+// 将提交的闭包在调用方的协程中同步执行, 或者放入队列中由测试通过 RunPending 执行, 使断言不再依赖协程的调度顺序。
+package mocker
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"sync"
+
+	"github.com/tencent/goom/erro"
+	"github.com/tencent/goom/internal/logger"
+)
+
+// schedCacheKey 异步任务调度 mock 在 Builder 中的缓存 key
+const schedCacheKey = "sched"
+
+// errorType error 接口类型
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// schedTask 提交的异步任务
+type schedTask struct {
+	// fn 提交的闭包
+	fn reflect.Value
+	// group 提交任务的 Group 实例地址, 不是通过 Group 提交时为 0
+	group uintptr
+}
+
+// GoScheduler 异步任务调度 mock
+type GoScheduler struct {
+	lock sync.Mutex
+	// manual 为 true 时任务放入队列, 由 RunPending 执行; 否则在提交时同步执行
+	manual  bool
+	pending []schedTask
+	// errs Group 实例地址到第一个返回的错误的映射
+	errs map[uintptr]error
+	// builder Func、Method、Group 创建 mock 使用的 Builder
+	builder  *Builder
+	targets  []string
+	mockers  []Mocker
+	canceled bool
+}
+
+// Go 创建异步任务调度 mock, 同一个 builder 多次调用返回同一个 mock
+// 默认在提交任务的协程中同步执行任务, 调用 Manual 之后任务放入队列, 由 RunPending 执行
+// 注意: 只能 mock 提交任务的函数或方法, 直接使用 go 关键字启动的协程无法 mock, 可以将其改为通过函数提交
+func Go(b *Builder) *GoScheduler {
+	if mocker, ok := b.mockers[schedCacheKey]; ok && !mocker.Canceled() {
+		return mocker.(*GoScheduler)
+	}
+
+	m := &GoScheduler{errs: make(map[uintptr]error), builder: b}
+	b.cache(schedCacheKey, m)
+	logger.Consolefc(logger.DebugLevel, "mocker [%s] apply.", logger.Caller(5), m.String())
+	return m
+}
+
+// Manual 切换为手动调度: 提交的任务放入队列, 直到调用 RunPending 时才执行
+func (m *GoScheduler) Manual() *GoScheduler {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.manual = true
+	return m
+}
+
+// Func mock 提交任务的函数, 比如: Func(pool.Submit)
+// 函数参数中的所有闭包都作为任务调度, 闭包不能有参数; 函数返回零值, 闭包返回的错误会被忽略
+func (m *GoScheduler) Func(fn interface{}) *GoScheduler {
+	typ := reflect.TypeOf(fn)
+	if typ == nil || typ.Kind() != reflect.Func {
+		panic(erro.NewIllegalParamTypeError("fn", fmt.Sprintf("%T", fn), "func"))
+	}
+	m.checkTasks("fn", typ)
+	mocker := m.builder.Func(fn)
+	mocker.Apply(m.submitter(typ, false).Interface())
+	m.add(runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name(), mocker)
+	return m
+}
+
+// Method mock 提交任务的方法, 比如: Method(&Pool{}, "Submit"), 参数和返回值的处理同 Func
+func (m *GoScheduler) Method(instance interface{}, name string) *GoScheduler {
+	method := methodType(instance, name)
+	m.checkTasks(name, method)
+	mocker := m.builder.Struct(instance).Method(name)
+	mocker.Apply(m.submitter(method, false).Interface())
+	m.add(fmt.Sprintf("%T.%s", instance, name), mocker)
+	return m
+}
+
+// Group mock errgroup.Group 风格的方法: Go(func() error)、TryGo(func() error) bool(可选) 和 Wait() error
+// Go、TryGo 按照调度方式执行任务并记录每个 Group 实例第一个返回的错误, TryGo 始终返回 true;
+// Wait 执行该实例在队列中的任务, 返回并清除记录的错误
+// 比如: Group(&errgroup.Group{}); 注意: errgroup.WithContext 创建的 context 不会因为任务返回错误而取消
+func (m *GoScheduler) Group(instance interface{}) *GoScheduler {
+	typ := fmt.Sprintf("%T", instance)
+	s := m.builder.Struct(instance)
+	for _, name := range []string{"Go", "TryGo"} {
+		if _, ok := reflect.TypeOf(instance).MethodByName(name); !ok && name == "TryGo" {
+			continue
+		}
+		method := methodType(instance, name)
+		m.checkTasks(name, method)
+		mocker := s.Method(name)
+		mocker.Apply(m.submitter(method, true).Interface())
+		m.add(typ+"."+name, mocker)
+	}
+
+	wait := methodType(instance, "Wait")
+	if wait.NumIn() != 1 || wait.NumOut() != 1 || wait.Out(0) != errorType {
+		panic(erro.NewIllegalParamTypeError("Wait", wait.String(), "func() error"))
+	}
+	mocker := s.Method("Wait")
+	mocker.Apply(reflect.MakeFunc(wait, func(args []reflect.Value) []reflect.Value {
+		err := m.wait(args[0].Pointer())
+		return []reflect.Value{reflectError(err)}
+	}).Interface())
+	m.add(typ+".Wait", mocker)
+	return m
+}
+
+// RunPending 按照提交的顺序执行队列中的任务, 包括执行过程中新提交的任务, 返回执行的任务数量
+func (m *GoScheduler) RunPending() int {
+	return m.run(func(schedTask) bool {
+		return true
+	})
+}
+
+// Pending 队列中等待执行的任务数量
+func (m *GoScheduler) Pending() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return len(m.pending)
+}
+
+// Apply 不支持, 请使用 Func、Method 或者 Group 指定提交任务的函数
+func (m *GoScheduler) Apply(interface{}) {
+	panic("GoScheduler does not support Apply, use Func(fn), Method(instance, name) or Group(instance) instead.")
+}
+
+// Cancel 取消 mock, 恢复异步执行任务, 队列中的任务会被丢弃
+func (m *GoScheduler) Cancel() {
+	for _, mocker := range m.mockers {
+		mocker.Cancel()
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.pending = nil
+	m.canceled = true
+}
+
+// Canceled 是否取消了 mock
+func (m *GoScheduler) Canceled() bool {
+	return m.canceled
+}
+
+// String mock 的名称或描述, 方便调试和问题排查
+func (m *GoScheduler) String() string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return fmt.Sprintf("go%v[manual: %t, pending: %d]", m.targets, m.manual, len(m.pending))
+}
+
+// add 记录 mock 的目标
+func (m *GoScheduler) add(target string, mocker Mocker) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.targets = append(m.targets, target)
+	m.mockers = append(m.mockers, mocker)
+}
+
+// checkTasks 检查函数参数中至少有一个闭包, 并且闭包都没有参数
+func (m *GoScheduler) checkTasks(name string, typ reflect.Type) {
+	found := false
+	for i := 0; i < typ.NumIn(); i++ {
+		in := typ.In(i)
+		if in.Kind() != reflect.Func {
+			continue
+		}
+		if in.NumIn() != 0 {
+			panic(erro.NewIllegalParamTypeError(name, typ.String(), "func with func() parameters"))
+		}
+		found = true
+	}
+	if !found {
+		panic(erro.NewIllegalParamTypeError(name, typ.String(), "func with func() parameters"))
+	}
+}
+
+// submitter 创建提交任务的替换函数, group 为 true 时第一个参数为 Group 实例, 返回值中的 bool 为 true
+func (m *GoScheduler) submitter(typ reflect.Type, group bool) reflect.Value {
+	return reflect.MakeFunc(typ, func(args []reflect.Value) []reflect.Value {
+		var key uintptr
+		if group {
+			key = args[0].Pointer()
+		}
+		for _, arg := range args {
+			if arg.Kind() == reflect.Func && !arg.IsNil() {
+				m.submit(schedTask{fn: arg, group: key})
+			}
+		}
+		results := make([]reflect.Value, typ.NumOut())
+		for i := range results {
+			results[i] = reflect.Zero(typ.Out(i))
+			if group && typ.Out(i).Kind() == reflect.Bool {
+				results[i] = reflect.ValueOf(true).Convert(typ.Out(i))
+			}
+		}
+		return results
+	})
+}
+
+// submit 同步执行任务或者放入队列
+func (m *GoScheduler) submit(task schedTask) {
+	m.lock.Lock()
+	if m.manual {
+		m.pending = append(m.pending, task)
+		m.lock.Unlock()
+		return
+	}
+	m.lock.Unlock()
+	m.exec(task)
+}
+
+// exec 执行任务, 记录 Group 任务第一个返回的错误
+func (m *GoScheduler) exec(task schedTask) {
+	results := task.fn.Call(nil)
+	if task.group == 0 || len(results) == 0 {
+		return
+	}
+	last := results[len(results)-1]
+	if last.Type() != errorType || last.IsNil() {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, ok := m.errs[task.group]; !ok {
+		m.errs[task.group] = last.Interface().(error)
+	}
+}
+
+// run 按照顺序执行队列中满足条件的任务, 直到队列中没有满足条件的任务
+func (m *GoScheduler) run(filter func(schedTask) bool) int {
+	count := 0
+	for {
+		m.lock.Lock()
+		index := -1
+		for i, task := range m.pending {
+			if filter(task) {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			m.lock.Unlock()
+			return count
+		}
+		task := m.pending[index]
+		m.pending = append(m.pending[:index:index], m.pending[index+1:]...)
+		m.lock.Unlock()
+
+		m.exec(task)
+		count++
+	}
+}
+
+// wait 执行 Group 实例在队列中的任务, 返回并清除记录的错误
+func (m *GoScheduler) wait(group uintptr) error {
+	m.run(func(task schedTask) bool {
+		return task.group == group
+	})
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	err := m.errs[group]
+	delete(m.errs, group)
+	return err
+}
+
+// methodType 获取方法的类型, 第一个参数为接收者
+func methodType(instance interface{}, name string) reflect.Type {
+	method, ok := reflect.TypeOf(instance).MethodByName(name)
+	if !ok {
+		panic(erro.NewFuncNotFoundError(fmt.Sprintf("%T.%s", instance, name)))
+	}
+	return method.Type
+}
+
+// reflectError 将 error 转换为 error 接口类型的值, nil 时为零值
+func reflectError(err error) reflect.Value {
+	if err == nil {
+		return reflect.Zero(errorType)
+	}
+	return reflect.ValueOf(&err).Elem()
+}
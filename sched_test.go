@@ -0,0 +1,153 @@
+package mocker_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/tencent/goom"
+)
+
+// TestUnitSchedTestSuite 测试入口
+func TestUnitSchedTestSuite(t *testing.T) {
+	suite.Run(t, new(schedTestSuite))
+}
+
+type schedTestSuite struct {
+	suite.Suite
+}
+
+// TestUnitSchedFunc 测试提交任务的函数同步执行
+func (s *schedTestSuite) TestUnitSchedFunc() {
+	mock := mocker.Create()
+	defer mock.Reset()
+	mocker.Go(mock).Func(submit)
+
+	count := 0
+	for i := 0; i < 3; i++ {
+		submit(func() {
+			count++
+		})
+	}
+	s.Equal(3, count, "sync check")
+}
+
+// TestUnitSchedManual 测试手动调度的任务在 RunPending 时按顺序执行
+func (s *schedTestSuite) TestUnitSchedManual() {
+	mock := mocker.Create()
+	defer mock.Reset()
+	sched := mocker.Go(mock).Manual().Method(&workerPool{}, "Submit")
+
+	pool := &workerPool{}
+	var order []int
+	for i := 0; i < 3; i++ {
+		i := i
+		s.NoError(pool.Submit(func() {
+			order = append(order, i)
+			if i == 0 {
+				_ = pool.Submit(func() {
+					order = append(order, 3)
+				})
+			}
+		}), "submit check")
+	}
+	s.Empty(order, "pending check")
+	s.Equal(3, sched.Pending(), "pending check")
+	s.Equal(4, sched.RunPending(), "run check")
+	s.Equal([]int{0, 1, 2, 3}, order, "order check")
+	s.Equal(0, sched.Pending(), "pending check")
+}
+
+// TestUnitSchedGroup 测试 errgroup 风格的 Group 返回第一个错误
+func (s *schedTestSuite) TestUnitSchedGroup() {
+	mock := mocker.Create()
+	defer mock.Reset()
+	sched := mocker.Go(mock).Manual().Group(&taskGroup{})
+
+	g1, g2 := &taskGroup{}, &taskGroup{}
+	errFirst := errors.New("first")
+	var done []string
+	g1.Go(func() error {
+		done = append(done, "g1-1")
+		return errFirst
+	})
+	s.True(g1.TryGo(func() error {
+		done = append(done, "g1-2")
+		return errors.New("second")
+	}), "try go check")
+	g2.Go(func() error {
+		done = append(done, "g2")
+		return nil
+	})
+
+	s.Equal(errFirst, g1.Wait(), "first error check")
+	s.Equal([]string{"g1-1", "g1-2"}, done, "group tasks check")
+	s.Equal(1, sched.Pending(), "other group check")
+	s.NoError(g2.Wait(), "no error check")
+	s.NoError(g1.Wait(), "error reset check")
+}
+
+// TestUnitSchedCancel 测试取消之后恢复异步执行
+func (s *schedTestSuite) TestUnitSchedCancel() {
+	mock := mocker.Create()
+	mocker.Go(mock).Func(submit)
+	mock.Reset()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	submit(wg.Done)
+	wg.Wait()
+}
+
+// submit 使用新的协程执行任务
+func submit(task func()) {
+	go task()
+}
+
+// workerPool 协程池
+type workerPool struct {
+	wg sync.WaitGroup
+}
+
+// Submit 提交任务
+func (p *workerPool) Submit(task func()) error {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		task()
+	}()
+	return nil
+}
+
+// taskGroup errgroup.Group 风格的任务组
+type taskGroup struct {
+	wg   sync.WaitGroup
+	once sync.Once
+	err  error
+}
+
+// Go 在新的协程中执行任务
+func (g *taskGroup) Go(f func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := f(); err != nil {
+			g.once.Do(func() {
+				g.err = err
+			})
+		}
+	}()
+}
+
+// TryGo 在新的协程中执行任务
+func (g *taskGroup) TryGo(f func() error) bool {
+	g.Go(f)
+	return true
+}
+
+// Wait 等待所有任务完成
+func (g *taskGroup) Wait() error {
+	g.wg.Wait()
+	return g.err
+}
@@ -0,0 +1,96 @@
+// Package mocker 定义了 mock 的外层用户使用 API 定义,
+// 包括函数、方法、接口、未导出函数(或方法的)的 Mocker 的实现。
+// 当前文件实现了未导出函数(或方法)签名的推导和校验:
+// 在编译时保留了调试信息(-ldflags="-s=false -w=false")的情况下, 从 DWARF 中读取函数的参数和返回值类型,
+// 使得未导出函数可以不调用 As 直接使用 When、Return 等 API, 同时校验 As 模板函数的签名。
+package mocker
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/tencent/goom/erro"
+	"github.com/tencent/goom/internal/unexports2"
+)
+
+// inferFuncDef 根据调试信息推导未导出函数(或方法)的函数定义
+func inferFuncDef(name string) interface{} {
+	funcTyp, err := unexports2.FuncSignature(name)
+	if err != nil {
+		panic(erro.NewTraceableErrorc("infer signature of "+name+
+			" error, please use As() to specify the func definition", err))
+	}
+	originFuncPtr, err := unexports2.FindFuncByName(name)
+	if err != nil {
+		panic(err)
+	}
+	return unexports2.NewFuncWithCodePtr(funcTyp, originFuncPtr).Interface()
+}
+
+// checkSignature 校验 As 模板函数签名和调试信息中的签名是否一致
+// 模板函数可以使用内存布局相同的类型代替未导出类型, 因此只校验参数个数、大小和类别;
+// 没有调试信息时不做校验
+func checkSignature(name string, funcDef interface{}) {
+	typ := reflect.TypeOf(funcDef)
+	if typ == nil || typ.Kind() != reflect.Func {
+		panic(erro.NewIllegalParamTypeError("funcDef", fmt.Sprintf("%T", funcDef), "func"))
+	}
+	expect, err := unexports2.FuncSignature(name)
+	if err != nil {
+		return
+	}
+	if reason := signatureDiff(typ, expect); reason != "" {
+		panic(erro.NewSignatureNotMatchError(name, typ.String(), expect.String(), reason))
+	}
+}
+
+// signatureDiff 比较两个函数签名, 返回不匹配的原因, 匹配时返回空字符串
+func signatureDiff(typ, expect reflect.Type) string {
+	if typ.NumIn() != expect.NumIn() {
+		return fmt.Sprintf("args length: %d, expect: %d", typ.NumIn(), expect.NumIn())
+	}
+	if typ.NumOut() != expect.NumOut() {
+		return fmt.Sprintf("returns length: %d, expect: %d", typ.NumOut(), expect.NumOut())
+	}
+	for i := 0; i < typ.NumIn(); i++ {
+		if !layoutCompatible(typ.In(i), expect.In(i)) {
+			return fmt.Sprintf("arg[%d] type: %s, expect: %s", i, typ.In(i), expect.In(i))
+		}
+	}
+	for i := 0; i < typ.NumOut(); i++ {
+		if !layoutCompatible(typ.Out(i), expect.Out(i)) {
+			return fmt.Sprintf("return[%d] type: %s, expect: %s", i, typ.Out(i), expect.Out(i))
+		}
+	}
+	return ""
+}
+
+// layoutCompatible 判断两个类型在调用约定上是否可以互相替代
+func layoutCompatible(a, b reflect.Type) bool {
+	if a == b {
+		return true
+	}
+	if a.Size() != b.Size() {
+		return false
+	}
+	ka, kb := kindClass(a.Kind()), kindClass(b.Kind())
+	// 结构体和数组只校验大小
+	return ka == kb || ka == reflect.Struct || kb == reflect.Struct
+}
+
+// kindClass 将类型归类, 同一类别的类型在寄存器分配上一致
+func kindClass(k reflect.Kind) reflect.Kind {
+	switch k {
+	case reflect.Ptr, reflect.UnsafePointer, reflect.Map, reflect.Chan, reflect.Func:
+		return reflect.UnsafePointer
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr, reflect.Bool:
+		return reflect.Int
+	case reflect.Float32, reflect.Float64:
+		return reflect.Float64
+	case reflect.Struct, reflect.Array:
+		return reflect.Struct
+	default:
+		return k
+	}
+}
@@ -0,0 +1,449 @@
+// Package mocker 定义了 mock 的外层用户使用 API 定义,
+// 包括函数、方法、接口、未导出函数(或方法的)的 Mocker 的实现。
+// 当前文件实现了接口真实实现的间谍(Spy)能力:
+// 生成的接口变量默认将所有方法委托给真实实现, 并记录每一次调用, 可以按方法选择性地使用 When 进行覆盖。
+package mocker
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"unsafe"
+
+	"github.com/tencent/goom/erro"
+	"github.com/tencent/goom/internal/hack"
+	"github.com/tencent/goom/internal/iface"
+	"github.com/tencent/goom/internal/logger"
+	"github.com/tencent/goom/internal/unexports2"
+)
+
+// Invocation 一次 mock 调用的记录
+type Invocation struct {
+	// Name 被调用的函数或方法名
+	Name string
+	// Args 调用参数(不包含接收体)
+	Args []interface{}
+	// Results 调用返回值
+	Results []interface{}
+}
+
+// SpyMocker 接口真实实现的间谍
+// 通过 As 指定接口类型之后, 生成的接口变量的所有方法默认委托给真实实现,
+// 每一次调用都会被记录下来, 可以使用 Method(name) 对指定方法进行覆盖
+type SpyMocker struct {
+	impl    interface{}
+	typ     reflect.Type
+	ctx     *iface.IContext
+	methods map[string]*SpyMethodMocker
+
+	// realVar 持有真实实现的接口变量, 防止被回收
+	realVar reflect.Value
+	// real 真实实现的接口结构
+	real *hack.Iface
+	// fake 间谍接口结构
+	fake *hack.Iface
+
+	calls []*Invocation
+	// lock 保护 calls 和 methods, 间谍可能在多个协程中被调用
+	lock     sync.Mutex
+	injected bool
+	canceled bool
+}
+
+// NewSpyMocker 创建间谍 Mocker
+// impl 接口的真实实现, 不能为 nil
+func NewSpyMocker(impl interface{}) *SpyMocker {
+	if impl == nil {
+		panic(erro.NewIllegalParamError("impl", "nil"))
+	}
+	return &SpyMocker{
+		impl:    impl,
+		ctx:     iface.NewContext(),
+		methods: make(map[string]*SpyMethodMocker, 16),
+	}
+}
+
+// String mock 的名称或描述, 方便调试和问题排查
+func (m *SpyMocker) String() string {
+	if m.typ == nil {
+		return fmt.Sprintf("spy(%T)", m.impl)
+	}
+	return fmt.Sprintf("spy(%T as %s)", m.impl, m.typ.String())
+}
+
+// As 指定间谍的接口类型, 并生成委托给真实实现的接口结构
+// iFace 必须是接口类型的指针, 比如 (*I)(nil);
+// 如果传递的是接口变量的指针(比如&i), 则会同时将间谍设置到该变量
+func (m *SpyMocker) As(iFace interface{}) *SpyMocker {
+	t := reflect.TypeOf(iFace)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Interface {
+		panic(erro.NewIllegalParamTypeError("iFace", fmt.Sprintf("%T", iFace), "*interface"))
+	}
+	typ := t.Elem()
+	if m.typ != nil && m.typ != typ {
+		panic(erro.NewIllegalStatusError("Spy.As", "spy is already bound to "+m.typ.String()))
+	}
+	if m.typ == nil {
+		m.bind(typ)
+	}
+	if !reflect.ValueOf(iFace).IsNil() {
+		m.inject(iFace)
+	}
+	return m
+}
+
+// bind 绑定接口类型, 构造真实实现和间谍的接口结构
+func (m *SpyMocker) bind(typ reflect.Type) {
+	implTyp := reflect.TypeOf(m.impl)
+	if !implTyp.Implements(typ) {
+		panic(erro.NewIllegalParamTypeError("impl", implTyp.String(), typ.String()))
+	}
+	if typ.NumMethod() == 0 {
+		panic(erro.NewIllegalParamTypeError("iFace", typ.String(), "interface with methods"))
+	}
+
+	m.typ = typ
+	m.realVar = reflect.New(typ)
+	m.realVar.Elem().Set(reflect.ValueOf(m.impl))
+	m.real = (*hack.Iface)(unsafe.Pointer(m.realVar.Pointer()))
+
+	ctxTyp := reflect.TypeOf(&IContext{})
+	for i := 0; i < typ.NumMethod(); i++ {
+		methodTyp := prependIn(ctxTyp, typ.Method(i).Type)
+		itabFunc := iface.GenCallableMethod(m.ctx, reflect.Zero(methodTyp).Interface(), m.proxy(i))
+		if m.fake == nil {
+			m.fake = iface.MakeInterface(m.ctx, i, itabFunc, typ)
+		} else {
+			m.fake.Tab.Fun[i] = itabFunc
+		}
+	}
+}
+
+// Inject 将间谍设置到接口变量
+// iFace 必须是接口变量的指针, 比如&i; Mock 取消之后接口变量会恢复原来的值
+func (m *SpyMocker) Inject(iFace interface{}) *SpyMocker {
+	if m.typ == nil {
+		panic("must use As() API before call Inject()")
+	}
+	m.inject(iFace)
+	return m
+}
+
+// inject 将间谍设置到接口变量
+func (m *SpyMocker) inject(iFace interface{}) {
+	v := reflect.ValueOf(iFace)
+	if v.Kind() != reflect.Ptr || v.Type().Elem() != m.typ || v.IsNil() {
+		panic(erro.NewIllegalParamTypeError("iFace", fmt.Sprintf("%T", iFace), "*"+m.typ.String()))
+	}
+	gen := unsafe.Pointer(v.Pointer())
+	iface.BackUpTo(m.ctx, gen)
+	*(*hack.Iface)(gen) = *m.fake
+	m.injected = true
+	m.canceled = false
+	logger.Consolefc(logger.DebugLevel, "mocker [%s] apply.", logger.Caller(6), m.String())
+}
+
+// Method 指定要覆盖的方法, 未被覆盖或覆盖条件未匹配的调用将委托给真实实现
+func (m *SpyMocker) Method(name string) *SpyMethodMocker {
+	if m.typ == nil {
+		panic("must use As() API before call Method()")
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if mocker, ok := m.methods[name]; ok && !mocker.Canceled() {
+		return mocker
+	}
+	index, ok := iface.MethodIndex(m.typ, name)
+	if !ok {
+		panic(erro.NewFuncNotFoundErrorWithSuggestion(m.typ.String()+"."+name, iface.MethodNames(m.typ)))
+	}
+	mocker := newSpyMethodMocker(m, index)
+	m.methods[name] = mocker
+	return mocker
+}
+
+// Interface 返回持有间谍的接口变量的指针, 类型为 As 指定的接口类型的指针, 比如: i := *spy.Interface().(*I)
+// 适用于 As((*I)(nil)) 没有指定接口变量的场景; 返回的接口变量不会在 Mock 取消时恢复
+func (m *SpyMocker) Interface() interface{} {
+	if m.typ == nil {
+		panic("must use As() API before call Interface()")
+	}
+	v := reflect.New(m.typ)
+	*(*hack.Iface)(unsafe.Pointer(v.Pointer())) = *m.fake
+	return v.Interface()
+}
+
+// Calls 返回所有已记录的调用
+func (m *SpyMocker) Calls() []*Invocation {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	calls := make([]*Invocation, len(m.calls))
+	copy(calls, m.calls)
+	return calls
+}
+
+// CallsOf 返回指定方法已记录的调用
+func (m *SpyMocker) CallsOf(name string) []*Invocation {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	calls := make([]*Invocation, 0, len(m.calls))
+	for _, c := range m.calls {
+		if c.Name == name {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// Apply 间谍不支持整体 Apply, 请使用 Method(name).Apply(...)
+func (m *SpyMocker) Apply(interface{}) {
+	panic("spy does not support Apply, please use Method(name).Apply(...)")
+}
+
+// Cancel 取消所有方法的覆盖, 并恢复被设置的接口变量
+func (m *SpyMocker) Cancel() {
+	m.lock.Lock()
+	methods := make([]*SpyMethodMocker, 0, len(m.methods))
+	for _, v := range m.methods {
+		methods = append(methods, v)
+	}
+	m.lock.Unlock()
+	for _, v := range methods {
+		v.Cancel()
+	}
+	if m.injected {
+		m.ctx.Cancel()
+		m.injected = false
+	}
+	m.canceled = true
+}
+
+// Canceled 是否已经被取消
+func (m *SpyMocker) Canceled() bool {
+	return m.canceled
+}
+
+// proxy 生成第 index 个接口方法的代理函数
+func (m *SpyMocker) proxy(index int) iface.PFunc {
+	name := m.typ.Method(index).Name
+	return func(args []reflect.Value) []reflect.Value {
+		in := args[1:]
+		var results []reflect.Value
+		m.lock.Lock()
+		mocker, ok := m.methods[name]
+		m.lock.Unlock()
+		if ok {
+			results, ok = mocker.invoke(in)
+		}
+		if !ok {
+			results = m.callReal(index, in)
+		}
+		m.record(name, in, results)
+		return results
+	}
+}
+
+// callReal 调用真实实现的第 index 个接口方法
+func (m *SpyMocker) callReal(index int, in []reflect.Value) []reflect.Value {
+	methodTyp := m.typ.Method(index).Type
+	fn := unexports2.NewFuncWithCodePtr(
+		prependIn(reflect.TypeOf(unsafe.Pointer(nil)), methodTyp), m.real.Tab.Fun[index])
+	args := append([]reflect.Value{reflect.ValueOf(m.real.Data)}, in...)
+	if methodTyp.IsVariadic() {
+		return fn.CallSlice(args)
+	}
+	return fn.Call(args)
+}
+
+// record 记录调用
+func (m *SpyMocker) record(name string, in []reflect.Value, results []reflect.Value) {
+	invocation := &Invocation{
+		Name:    name,
+		Args:    values2I(in),
+		Results: values2I(results),
+	}
+	m.lock.Lock()
+	m.calls = append(m.calls, invocation)
+	m.lock.Unlock()
+}
+
+// SpyMethodMocker 间谍的方法覆盖 Mocker
+// 方法模板的第一个参数为真实实现的接收体类型, 后续的参数原样照抄接口方法
+type SpyMethodMocker struct {
+	*baseMocker
+	// lock 保护 when、imp 和 canceled, 覆盖的方法可能在多个协程中被调用
+	lock    sync.Mutex
+	spy     *SpyMocker
+	index   int
+	funcDef interface{}
+}
+
+// newSpyMethodMocker 创建间谍的方法覆盖 Mocker
+func newSpyMethodMocker(spy *SpyMocker, index int) *SpyMethodMocker {
+	funcTyp := prependIn(reflect.TypeOf(spy.impl), spy.typ.Method(index).Type)
+	return &SpyMethodMocker{
+		baseMocker: newBaseMocker(""),
+		spy:        spy,
+		index:      index,
+		funcDef:    reflect.Zero(funcTyp).Interface(),
+	}
+}
+
+// String mock 的名称或描述, 方便调试和问题排查
+func (m *SpyMethodMocker) String() string {
+	return fmt.Sprintf("%s.%s", m.spy.String(), m.spy.typ.Method(m.index).Name)
+}
+
+// Apply 指定覆盖方法的回调函数
+// callback 的第一个参数为真实实现的接收体, 后续的参数原样照抄接口方法
+func (m *SpyMethodMocker) Apply(callback interface{}) {
+	if reflect.TypeOf(callback) != reflect.TypeOf(m.funcDef) {
+		panic(erro.NewIllegalParamTypeError("callback",
+			reflect.TypeOf(callback).String(), reflect.TypeOf(m.funcDef).String()))
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.when = nil
+	m.imp = callback
+	m.canceled = false
+}
+
+// When 指定条件匹配, 条件未匹配的调用将委托给真实实现
+func (m *SpyMethodMocker) When(specArg ...interface{}) *When {
+	if when := m.currentWhen(); when != nil {
+		return when.When(specArg...)
+	}
+	when, err := m.createWhen(specArg, nil)
+	if err != nil {
+		panic(err)
+	}
+	if _, ok := when.defaultReturns.(*EmptyMatch); ok {
+		// 无返回值的方法, 条件未匹配时仍然委托给真实实现
+		when.defaultReturns = nil
+	}
+	m.setWhen(when)
+	return when
+}
+
+// Return 指定返回值
+func (m *SpyMethodMocker) Return(value ...interface{}) *When {
+	if when := m.currentWhen(); when != nil {
+		return when.Return(value...)
+	}
+	when, err := m.createWhen(nil, value)
+	if err != nil {
+		panic(err)
+	}
+	m.setWhen(when)
+	return when
+}
+
+// Returns 依次按顺序返回值
+func (m *SpyMethodMocker) Returns(values ...interface{}) *When {
+	if when := m.currentWhen(); when != nil {
+		return when.Returns(values...)
+	}
+	when, err := m.createWhen(nil, nil)
+	if err != nil {
+		panic(err)
+	}
+	m.setWhen(when)
+	when.Returns(values...)
+	return when
+}
+
+// Origin 指定真实实现的方法, originFunc 必须是方法模板函数变量的指针
+func (m *SpyMethodMocker) Origin(originFunc interface{}) ExportedMocker {
+	v := reflect.ValueOf(originFunc)
+	funcTyp := reflect.TypeOf(m.funcDef)
+	if v.Kind() != reflect.Ptr || v.Type().Elem() != funcTyp {
+		panic(erro.NewIllegalParamTypeError("originFunc", v.Type().String(), "*"+funcTyp.String()))
+	}
+	v.Elem().Set(reflect.MakeFunc(funcTyp, func(args []reflect.Value) []reflect.Value {
+		return m.spy.callReal(m.index, args[1:])
+	}))
+	return m
+}
+
+// Cancel 取消覆盖, 之后的调用委托给真实实现
+func (m *SpyMethodMocker) Cancel() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.imp = nil
+	m.baseMocker.Cancel()
+}
+
+// Canceled 是否已经被取消
+func (m *SpyMethodMocker) Canceled() bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.canceled
+}
+
+// currentWhen 当前的条件匹配
+func (m *SpyMethodMocker) currentWhen() *When {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.when
+}
+
+// createWhen 创建条件匹配, 条件的修改和匹配都使用方法的锁
+func (m *SpyMethodMocker) createWhen(args []interface{}, returns []interface{}) (*When, error) {
+	when, err := CreateWhen(m, m.funcDef, args, returns, true)
+	if err != nil {
+		return nil, err
+	}
+	when.locker = &m.lock
+	return when, nil
+}
+
+// setWhen 设置条件匹配
+func (m *SpyMethodMocker) setWhen(when *When) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.imp = nil
+	m.when = when
+	m.canceled = false
+}
+
+// invoke 执行覆盖逻辑, 返回 false 表示需要委托给真实实现
+func (m *SpyMethodMocker) invoke(in []reflect.Value) ([]reflect.Value, bool) {
+	args := append([]reflect.Value{reflect.ValueOf(m.spy.impl)}, in...)
+	m.lock.Lock()
+	if m.canceled {
+		m.lock.Unlock()
+		return nil, false
+	}
+	if m.when != nil {
+		defer m.lock.Unlock()
+		return m.when.tryInvoke(args)
+	}
+	imp := m.imp
+	m.lock.Unlock()
+	if imp != nil {
+		if reflect.TypeOf(imp).IsVariadic() {
+			return reflect.ValueOf(imp).CallSlice(args), true
+		}
+		return reflect.ValueOf(imp).Call(args), true
+	}
+	return nil, false
+}
+
+// values2I 将 reflect.Value 列表转换为 interface{} 列表
+func values2I(values []reflect.Value) []interface{} {
+	result := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		result = append(result, v.Interface())
+	}
+	return result
+}
+
+// prependIn 在函数类型的参数列表前添加一个参数类型
+func prependIn(first reflect.Type, funcTyp reflect.Type) reflect.Type {
+	in := make([]reflect.Type, 0, funcTyp.NumIn()+1)
+	in = append(in, first)
+	for i := 0; i < funcTyp.NumIn(); i++ {
+		in = append(in, funcTyp.In(i))
+	}
+	return reflect.FuncOf(in, outTypes(funcTyp), funcTyp.IsVariadic())
+}
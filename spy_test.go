@@ -0,0 +1,161 @@
+// Package mocker_test 对 mocker 包的测试
+// 当前文件实现了对 spy.go 的单测
+package mocker_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	mocker "github.com/tencent/goom"
+)
+
+// TestUnitSpyTestSuite 测试入口
+func TestUnitSpyTestSuite(t *testing.T) {
+	suite.Run(t, new(spyMockerTestSuite))
+}
+
+type spyMockerTestSuite struct {
+	suite.Suite
+}
+
+// realI 接口 I 的真实实现
+type realI struct {
+	base int
+}
+
+func (r *realI) Call(i int) int {
+	return r.base + i
+}
+
+func (r *realI) Call1(s string) string {
+	return "real:" + s
+}
+
+func (r *realI) call2(i int32) int32 {
+	return i * 2
+}
+
+// TestUnitSpyDelegate 测试间谍默认委托给真实实现并记录调用
+func (s *spyMockerTestSuite) TestUnitSpyDelegate() {
+	s.Run("success", func() {
+		mock := mocker.Create()
+
+		var i I = &realI{base: 10}
+		spy := mock.Spy(i).As(&i)
+
+		s.Equal(11, i.Call(1), "spy delegate check")
+		s.Equal("real:a", i.Call1("a"), "spy delegate check")
+
+		calls := spy.Calls()
+		s.Equal(2, len(calls), "spy calls check")
+		s.Equal("Call", calls[0].Name, "spy calls name check")
+		s.Equal([]interface{}{1}, calls[0].Args, "spy calls args check")
+		s.Equal([]interface{}{11}, calls[0].Results, "spy calls results check")
+		s.Equal(1, len(spy.CallsOf("Call1")), "spy calls of check")
+
+		mock.Reset()
+		s.Equal(11, i.Call(1), "spy reset check")
+		_, isReal := i.(*realI)
+		s.True(isReal, "spy reset check")
+	})
+}
+
+// TestUnitSpyMethodWhen 测试间谍覆盖指定方法, 未匹配的调用委托给真实实现
+func (s *spyMockerTestSuite) TestUnitSpyMethodWhen() {
+	s.Run("success", func() {
+		mock := mocker.Create()
+
+		var i I = &realI{base: 10}
+		spy := mock.Spy(i).As(&i)
+		spy.Method("Call").When(1).Return(100)
+
+		s.Equal(100, i.Call(1), "spy when check")
+		s.Equal(12, i.Call(2), "spy when delegate check")
+		s.Equal("real:b", i.Call1("b"), "spy other method check")
+
+		spy.Method("Call1").Apply(func(r *realI, str string) string {
+			return "fake:" + str
+		})
+		s.Equal("fake:b", i.Call1("b"), "spy apply check")
+
+		var origin func(*realI, string) string
+		spy.Method("Call1").Origin(&origin)
+		s.Equal("real:c", origin(nil, "c"), "spy origin check")
+
+		mock.Reset()
+		s.Equal(11, i.Call(1), "spy reset check")
+		s.Equal("real:b", i.Call1("b"), "spy reset check")
+	})
+}
+
+// TestUnitSpyMethodReturn 测试间谍方法覆盖返回值
+func (s *spyMockerTestSuite) TestUnitSpyMethodReturn() {
+	s.Run("success", func() {
+		mock := mocker.Create()
+		defer mock.Reset()
+
+		var i I = &realI{base: 10}
+		mock.Spy(i).As(&i).Method("Call").Returns(1, 2)
+
+		s.Equal(1, i.Call(5), "spy returns check")
+		s.Equal(2, i.Call(5), "spy returns check")
+		s.Equal(2, i.Call(5), "spy returns check")
+	})
+}
+
+// TestUnitSpyInterface 测试通过 As((*I)(nil)) 创建间谍并获取接口变量
+func (s *spyMockerTestSuite) TestUnitSpyInterface() {
+	s.Run("success", func() {
+		mock := mocker.Create()
+		defer mock.Reset()
+
+		spy := mock.Spy(&realI{base: 10}).As((*I)(nil))
+		i := *spy.Interface().(*I)
+		s.Equal(11, i.Call(1), "spy interface delegate check")
+
+		spy.Method("Call").When(1).Return(100)
+		s.Equal(100, i.Call(1), "spy interface mock check")
+		s.Equal(2, len(spy.CallsOf("Call")), "spy interface calls check")
+	})
+}
+
+// TestUnitSpyConcurrent 测试在多个协程中调用间谍的同时覆盖方法
+func (s *spyMockerTestSuite) TestUnitSpyConcurrent() {
+	s.Run("success", func() {
+		mock := mocker.Create()
+		defer mock.Reset()
+
+		var i I = &realI{base: 10}
+		spy := mock.Spy(i).As(&i)
+		var wg sync.WaitGroup
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for n := 0; n < 100; n++ {
+					_ = i.Call(1)
+					_ = i.Call1("a")
+				}
+			}()
+		}
+		spy.Method("Call1").When("b").Return("fake")
+		wg.Wait()
+		s.Equal(800, len(spy.Calls()), "spy concurrent calls check")
+		s.Equal("fake", i.Call1("b"), "spy concurrent mock check")
+	})
+}
+
+// TestUnitSpyCache 测试同一个指针的间谍被复用, 非指针类型的 impl 不会相互冲突
+func (s *spyMockerTestSuite) TestUnitSpyCache() {
+	s.Run("success", func() {
+		mock := mocker.Create()
+		defer mock.Reset()
+
+		impl := &realI{base: 10}
+		s.Same(mock.Spy(impl), mock.Spy(impl), "pointer impl cache check")
+		s.False(mock.Spy(impl) == mock.Spy(&realI{base: 10}), "distinct pointer impl check")
+		s.False(mock.Spy(realI{base: 1}) == mock.Spy(realI{base: 2}), "value impl check")
+		s.Equal("spy(mocker_test.realI)", mock.Spy(realI{}).String(), "value impl string check")
+	})
+}
@@ -0,0 +1,173 @@
+package sqlmock
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+)
+
+// connector 内部驱动的连接器, 所有连接共享同一个 Mock 的匹配条件
+type connector struct {
+	mock *Mock
+}
+
+// Connect 创建连接
+func (c *connector) Connect(context.Context) (driver.Conn, error) {
+	return &conn{mock: c.mock}, nil
+}
+
+// Driver 返回驱动
+func (c *connector) Driver() driver.Driver {
+	return mockDriver{}
+}
+
+// mockDriver 内部驱动, 只能通过 connector 创建连接
+type mockDriver struct{}
+
+// Open 不支持通过 DSN 打开
+func (mockDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("goom sqlmock: open by dsn is not supported")
+}
+
+// conn 内部驱动的连接, 按照 Mock 的匹配条件返回结果
+type conn struct {
+	mock *Mock
+}
+
+// Prepare 不支持预编译语句
+func (c *conn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("goom sqlmock: prepared statement is not supported")
+}
+
+// Close 关闭连接
+func (c *conn) Close() error {
+	return nil
+}
+
+// Begin 开始事务
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx 开始事务
+func (c *conn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.mock.record("begin")
+	return &tx{mock: c.mock}, nil
+}
+
+// Ping 检查连接
+func (c *conn) Ping(context.Context) error {
+	return nil
+}
+
+// CheckNamedValue 接受任意类型的参数, 匹配时使用调用方传入的原始值
+func (c *conn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+// QueryContext 查询
+func (c *conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	e, err := c.mock.find("query", query, values(args))
+	if err != nil {
+		return nil, err
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	data := make([][]driver.Value, 0, len(e.rows))
+	for _, row := range e.rows {
+		values := make([]driver.Value, len(row))
+		for i, v := range row {
+			if values[i], err = driver.DefaultParameterConverter.ConvertValue(v); err != nil {
+				return nil, err
+			}
+		}
+		data = append(data, values)
+	}
+	return &rows{columns: e.columns, data: data}, nil
+}
+
+// ExecContext 执行
+func (c *conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, err := c.mock.find("exec", query, values(args))
+	if err != nil {
+		return nil, err
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	if e.result == nil {
+		return result{}, nil
+	}
+	return e.result, nil
+}
+
+// tx 内部驱动的事务
+type tx struct {
+	mock *Mock
+}
+
+// Commit 提交事务
+func (t *tx) Commit() error {
+	t.mock.record("commit")
+	return nil
+}
+
+// Rollback 回滚事务
+func (t *tx) Rollback() error {
+	t.mock.record("rollback")
+	return nil
+}
+
+// rows 查询返回的行
+type rows struct {
+	columns []string
+	data    [][]driver.Value
+	next    int
+}
+
+// Columns 列名
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+// Close 关闭
+func (r *rows) Close() error {
+	return nil
+}
+
+// Next 读取下一行
+func (r *rows) Next(dest []driver.Value) error {
+	if r.next >= len(r.data) {
+		return io.EOF
+	}
+	copy(dest, r.data[r.next])
+	r.next++
+	return nil
+}
+
+// result 执行的结果
+type result struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+// LastInsertId 自增 ID
+func (r result) LastInsertId() (int64, error) {
+	return r.lastInsertID, nil
+}
+
+// RowsAffected 影响的行数
+func (r result) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
+
+// values 转换为参数值
+func values(args []driver.NamedValue) []interface{} {
+	values := make([]interface{}, 0, len(args))
+	for _, a := range args {
+		values = append(values, a.Value)
+	}
+	return values
+}
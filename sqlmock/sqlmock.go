@@ -0,0 +1,302 @@
+// Package sqlmock 基于 goom 的方法 mock 实现的 database/sql mock。
+// mock 之后 (*sql.DB).QueryContext、ExecContext、BeginTx、PingContext 以及基于它们的 Query、QueryRow、Exec、Begin 等,
+// 都转发到 sqlmock 内部的 *sql.DB 上执行, 内部的驱动按照 Expect 设定的 SQL 和参数匹配返回的结果,
+// 返回的 *sql.Rows、*sql.Row、*sql.Tx 都是 database/sql 的真实对象, 行的读取(Next、Scan)和类型转换和真实的驱动一致;
+// 被测代码自己打开的 *sql.DB 不需要替换驱动。
+// 注意: 需要在测试时关闭内联: -gcflags="all=-l"; 不支持 Prepare 和 (*sql.DB).Conn
+package sqlmock
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	mocker "github.com/tencent/goom"
+	"github.com/tencent/goom/arg"
+	"github.com/tencent/goom/erro"
+)
+
+var (
+	// active 当前生效的 mock, database/sql 的方法是进程全局的, 同一时刻只能有一个 Mock 生效
+	active     *Mock
+	activeLock sync.Mutex
+)
+
+// cacheKey Mock 在 Builder 中的缓存 key
+type cacheKey struct{}
+
+// Call 一次 SQL 执行记录
+type Call struct {
+	// Kind 类型: query、exec、begin、commit、rollback
+	Kind string
+	// Query SQL 语句, 事务操作时为空
+	Query string
+	// Args SQL 参数
+	Args []interface{}
+}
+
+// Expectation SQL 和参数的匹配条件以及返回的结果
+type Expectation struct {
+	kind    string
+	query   arg.Expr
+	args    []arg.Expr
+	columns []string
+	rows    [][]interface{}
+	result  sql.Result
+	err     error
+}
+
+// WillReturnRows 指定查询返回的列和行
+func (e *Expectation) WillReturnRows(columns []string, rows ...[]interface{}) *Expectation {
+	e.columns = columns
+	e.rows = rows
+	return e
+}
+
+// WillReturnResult 指定执行返回的自增 ID 和影响的行数
+func (e *Expectation) WillReturnResult(lastInsertID, rowsAffected int64) *Expectation {
+	e.result = result{lastInsertID: lastInsertID, rowsAffected: rowsAffected}
+	return e
+}
+
+// WillReturnError 指定返回的错误
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// match 匹配 SQL 类型、语句和参数
+func (e *Expectation) match(kind, query string, args []interface{}) bool {
+	if e.kind != kind || !eval(e.query, reflect.ValueOf(query)) {
+		return false
+	}
+	if e.args == nil {
+		return true
+	}
+	if len(e.args) != len(args) {
+		return false
+	}
+	for i, expr := range e.args {
+		// 使用 interface{} 类型的值, 兼容 nil 参数
+		if !eval(expr, reflect.ValueOf(&args[i]).Elem()) {
+			return false
+		}
+	}
+	return true
+}
+
+// eval 执行参数表达式
+func eval(expr arg.Expr, v reflect.Value) bool {
+	ok, err := expr.Eval([]reflect.Value{v}, false)
+	return err == nil && ok
+}
+
+// Mock database/sql mock
+type Mock struct {
+	lock         sync.Mutex
+	expectations []*Expectation
+	calls        []Call
+	// db 内部驱动的 *sql.DB, 被测代码的 *sql.DB 上的调用都转发到 db
+	db *sql.DB
+
+	mockers      []mocker.Mocker
+	canceled     bool
+	queryContext func(db *sql.DB, ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	execContext  func(db *sql.DB, ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	beginTx      func(db *sql.DB, ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// New 在 builder 上 mock database/sql, 同一个 builder 重复调用时返回同一个 Mock; builder.Reset() 之后恢复
+// 其它 builder 创建的 Mock 未取消时 panic, 需要先调用其 builder.Reset()
+func New(b *mocker.Builder) *Mock {
+	if cached, ok := b.Cached(cacheKey{}); ok {
+		return cached.(*Mock)
+	}
+	activeLock.Lock()
+	defer activeLock.Unlock()
+	if active != nil && !active.Canceled() {
+		panic(erro.NewIllegalStatusError("sqlmock.New",
+			"another sqlmock is active, reset its builder first: "+active.String()))
+	}
+
+	m := &Mock{
+		queryContext: queryContextTrampoline,
+		execContext:  execContextTrampoline,
+		beginTx:      beginTxTrampoline,
+	}
+	m.db = sql.OpenDB(&connector{mock: m})
+	db := b.Struct(&sql.DB{})
+	query := db.Method("QueryContext")
+	query.Origin(&m.queryContext).Apply(m.doQueryContext)
+	exec := db.Method("ExecContext")
+	exec.Origin(&m.execContext).Apply(m.doExecContext)
+	begin := db.Method("BeginTx")
+	begin.Origin(&m.beginTx).Apply(m.doBeginTx)
+	ping := db.Method("PingContext")
+	ping.Apply(func(*sql.DB, context.Context) error {
+		return nil
+	})
+	m.mockers = []mocker.Mocker{query, exec, begin, ping}
+	active = m
+	b.Cache(cacheKey{}, m)
+	return m
+}
+
+// Apply 不支持, 请使用 ExpectQuery 或 ExpectExec
+func (m *Mock) Apply(interface{}) {
+	panic("sqlmock does not support Apply, use ExpectQuery or ExpectExec instead.")
+}
+
+// Cancel 取消 mock, 恢复执行真实的 database/sql 方法
+func (m *Mock) Cancel() {
+	for _, mocker := range m.mockers {
+		mocker.Cancel()
+	}
+	// 取消 mock 之后关闭内部驱动的 *sql.DB, 释放连接和后台协程
+	_ = m.db.Close()
+	m.canceled = true
+}
+
+// Canceled 是否取消了 mock
+func (m *Mock) Canceled() bool {
+	return m.canceled
+}
+
+// String mock 的名称或描述, 方便调试和问题排查
+func (m *Mock) String() string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return fmt.Sprintf("sqlmock[expectations: %d, calls: %d]", len(m.expectations), len(m.calls))
+}
+
+// ExpectQuery 添加查询的匹配条件, 按照添加的顺序匹配, 可以重复匹配
+// query SQL 语句的正则表达式, 或者参数表达式, 比如: arg.HasPrefix("SELECT")
+// args SQL 参数, 可以是值或者参数表达式, 比如: arg.Any()、arg.In(1, 2); 不指定时匹配任意参数
+func (m *Mock) ExpectQuery(query interface{}, args ...interface{}) *Expectation {
+	return m.expect("query", query, args)
+}
+
+// ExpectExec 添加执行的匹配条件, 默认返回的自增 ID 和影响的行数都为 0, 参数同 ExpectQuery
+func (m *Mock) ExpectExec(query interface{}, args ...interface{}) *Expectation {
+	e := m.expect("exec", query, args)
+	e.result = result{}
+	return e
+}
+
+// Calls 获取执行过的 SQL 和事务操作, 包括没有匹配条件的 SQL
+func (m *Mock) Calls() []Call {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return append([]Call(nil), m.calls...)
+}
+
+// DB 内部驱动的 *sql.DB, 也可以直接传给被测代码
+func (m *Mock) DB() *sql.DB {
+	return m.db
+}
+
+func (m *Mock) expect(kind string, query interface{}, args []interface{}) *Expectation {
+	e := &Expectation{kind: kind, query: queryExpr(query)}
+	if len(args) > 0 {
+		types := make([]reflect.Type, len(args))
+		for i := range types {
+			types[i] = reflect.TypeOf((*interface{})(nil)).Elem()
+		}
+		exprs, err := arg.ToExpr(args, types, false)
+		if err != nil {
+			panic(err)
+		}
+		e.args = exprs
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.expectations = append(m.expectations, e)
+	return e
+}
+
+// find 记录调用并查找匹配的条件
+func (m *Mock) find(kind, query string, args []interface{}) (*Expectation, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.calls = append(m.calls, Call{Kind: kind, Query: query, Args: args})
+	for _, e := range m.expectations {
+		if e.match(kind, query, args) {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("goom sqlmock: no expectation matched %s [%s], args: %v", kind, query, args)
+}
+
+// record 记录事务操作
+func (m *Mock) record(kind string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.calls = append(m.calls, Call{Kind: kind})
+}
+
+func (m *Mock) doQueryContext(_ *sql.DB, ctx context.Context, query string,
+	args ...interface{}) (*sql.Rows, error) {
+	return m.queryContext(m.db, ctx, query, args...)
+}
+
+func (m *Mock) doExecContext(_ *sql.DB, ctx context.Context, query string,
+	args ...interface{}) (sql.Result, error) {
+	return m.execContext(m.db, ctx, query, args...)
+}
+
+func (m *Mock) doBeginTx(_ *sql.DB, ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return m.beginTx(m.db, ctx, opts)
+}
+
+// queryExpr SQL 语句的匹配表达式, 字符串作为正则表达式
+func queryExpr(query interface{}) arg.Expr {
+	switch q := query.(type) {
+	case arg.Expr:
+		if err := q.Resolve([]reflect.Type{reflect.TypeOf("")}, false); err != nil {
+			panic(err)
+		}
+		return q
+	case string:
+		return &regexpExpr{re: regexp.MustCompile(q)}
+	default:
+		panic(erro.NewIllegalParamTypeError("query", fmt.Sprintf("%T", query), "string or arg.Expr"))
+	}
+}
+
+// regexpExpr SQL 语句的正则表达式匹配, 匹配前将连续的空白字符替换为一个空格
+type regexpExpr struct {
+	re *regexp.Regexp
+}
+
+// Resolve 解析参数类型
+func (r *regexpExpr) Resolve([]reflect.Type, bool) error {
+	return nil
+}
+
+// Eval 执行正则表达式匹配
+func (r *regexpExpr) Eval(input []reflect.Value, _ bool) (bool, error) {
+	return r.re.MatchString(strings.Join(strings.Fields(input[0].String()), " ")), nil
+}
+
+// 以下为调用原方法的跳板函数占位, mock 之后函数体会被替换, 实际不会执行该函数体, 但是必须编写
+
+func queryContextTrampoline(db *sql.DB, ctx context.Context, query string,
+	args ...interface{}) (*sql.Rows, error) {
+	fmt.Println("only for placeholder, will not call", db, ctx, query, args)
+	return nil, nil
+}
+
+func execContextTrampoline(db *sql.DB, ctx context.Context, query string,
+	args ...interface{}) (sql.Result, error) {
+	fmt.Println("only for placeholder, will not call", db, ctx, query, args)
+	return nil, nil
+}
+
+func beginTxTrampoline(db *sql.DB, ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	fmt.Println("only for placeholder, will not call", db, ctx, opts)
+	return nil, nil
+}
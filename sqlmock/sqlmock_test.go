@@ -0,0 +1,140 @@
+package sqlmock_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/tencent/goom"
+	"github.com/tencent/goom/arg"
+	"github.com/tencent/goom/sqlmock"
+)
+
+// TestUnitSQLMockTestSuite 测试入口
+func TestUnitSQLMockTestSuite(t *testing.T) {
+	suite.Run(t, new(sqlMockTestSuite))
+}
+
+type sqlMockTestSuite struct {
+	suite.Suite
+}
+
+func init() {
+	sql.Register("goom-unreachable", unreachableDriver{})
+}
+
+// unreachableDriver 无法建立连接的驱动, 模拟被测代码连接真实的数据库
+type unreachableDriver struct{}
+
+// Open 建立连接
+func (unreachableDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("connection refused")
+}
+
+// openDB 被测代码自己打开的 *sql.DB
+func openDB() *sql.DB {
+	db, err := sql.Open("goom-unreachable", "user:pass@tcp(127.0.0.1:3306)/app")
+	if err != nil {
+		panic(err)
+	}
+	return db
+}
+
+// TestUnitQuery 测试查询
+func (s *sqlMockTestSuite) TestUnitQuery() {
+	mock := mocker.Create()
+	defer mock.Reset()
+	m := sqlmock.New(mock)
+	m.ExpectQuery("SELECT id, name FROM users WHERE id = \\?", 1).
+		WillReturnRows([]string{"id", "name"}, []interface{}{1, "alice"})
+	m.ExpectQuery(arg.HasPrefix("SELECT id, name FROM users")).
+		WillReturnRows([]string{"id", "name"}, []interface{}{1, "alice"}, []interface{}{2, "bob"})
+	m.ExpectQuery("FROM orders").WillReturnError(errors.New("table not found"))
+
+	db := openDB()
+	s.NoError(db.Ping(), "ping check")
+
+	var name string
+	s.NoError(db.QueryRow("SELECT id, name FROM users WHERE id = ?", 1).Scan(new(int64), &name), "query row check")
+	s.Equal("alice", name, "query row check")
+
+	rows, err := db.Query("SELECT id, name FROM users")
+	s.Require().NoError(err, "query check")
+	var names []string
+	for rows.Next() {
+		var id int
+		s.NoError(rows.Scan(&id, &name), "scan check")
+		names = append(names, name)
+	}
+	s.NoError(rows.Close(), "close check")
+	s.Equal([]string{"alice", "bob"}, names, "query check")
+
+	_, err = db.Query("SELECT * FROM orders")
+	s.EqualError(err, "table not found", "error check")
+	err = db.QueryRow("SELECT * FROM unknown").Scan(&name)
+	s.Error(err, "no expectation check")
+
+	calls := m.Calls()
+	s.Len(calls, 4, "calls check")
+	s.Equal("SELECT id, name FROM users WHERE id = ?", calls[0].Query, "calls check")
+	s.Equal([]interface{}{1}, calls[0].Args, "calls args check")
+}
+
+// TestUnitExecAndTx 测试执行和事务
+func (s *sqlMockTestSuite) TestUnitExecAndTx() {
+	mock := mocker.Create()
+	defer mock.Reset()
+	m := sqlmock.New(mock)
+	m.ExpectExec("^INSERT INTO users", arg.Any(), "alice").WillReturnResult(10, 1)
+	m.ExpectExec("^DELETE").WillReturnError(errors.New("denied"))
+
+	db := openDB()
+	tx, err := db.Begin()
+	s.Require().NoError(err, "begin check")
+	result, err := tx.Exec("INSERT INTO users (id, name) VALUES (?, ?)", 10, "alice")
+	s.Require().NoError(err, "exec check")
+	id, _ := result.LastInsertId()
+	s.Equal(int64(10), id, "last insert id check")
+	_, err = tx.Exec("DELETE FROM users")
+	s.EqualError(err, "denied", "exec error check")
+	s.NoError(tx.Rollback(), "rollback check")
+
+	_, err = db.Exec("INSERT INTO users (id, name) VALUES (?, ?)", 11, "bob")
+	s.Error(err, "args not match check")
+
+	kinds := make([]string, 0)
+	for _, call := range m.Calls() {
+		kinds = append(kinds, call.Kind)
+	}
+	s.Equal([]string{"begin", "exec", "exec", "rollback", "exec"}, kinds, "calls check")
+	s.Same(m, sqlmock.New(mock), "cache check")
+
+	mock.Reset()
+	s.Error(db.Ping(), "reset check")
+	s.EqualError(m.DB().Ping(), "sql: database is closed", "inner db closed check")
+}
+
+// TestUnitMultiBuilder 测试其它 builder 的 Mock 生效时不能再创建 Mock, 取消之后可以重新创建
+func (s *sqlMockTestSuite) TestUnitMultiBuilder() {
+	mock1, mock2 := mocker.Create(), mocker.Create()
+	defer mock2.Reset()
+	m := sqlmock.New(mock1)
+	m.ExpectQuery("SELECT 1").WillReturnRows([]string{"n"}, []interface{}{1})
+
+	s.Panics(func() {
+		sqlmock.New(mock2)
+	}, "another active check")
+	var n int
+	s.NoError(openDB().QueryRow("SELECT 1").Scan(&n), "first mock kept check")
+	s.Equal(1, n, "first mock kept check")
+
+	mock1.Reset()
+	m2 := sqlmock.New(mock2)
+	s.False(m == m2, "new mock check")
+	m2.ExpectQuery("SELECT 1").WillReturnRows([]string{"n"}, []interface{}{2})
+	s.NoError(openDB().QueryRow("SELECT 1").Scan(&n), "second mock check")
+	s.Equal(2, n, "second mock check")
+	s.Len(m.Calls(), 1, "first mock calls check")
+}
@@ -0,0 +1,116 @@
+// Package mocker 定义了 mock 的外层用户使用 API 定义,
+// 包括函数、方法、接口、未导出函数(或方法的)的 Mocker 的实现。
+// 当前文件实现了符号的检索: 查找二进制中可以 mock 的函数、方法、闭包和全局变量,
+// 方便确定 ExportFunc、ExportStruct、Var 等 API 需要的符号名称。
+package mocker
+
+import (
+	"debug/gosym"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/tencent/goom/internal/unexports2"
+)
+
+// SymbolKind 符号类型
+type SymbolKind string
+
+const (
+	// SymbolFunc 函数
+	SymbolFunc SymbolKind = "func"
+	// SymbolMethod 方法
+	SymbolMethod SymbolKind = "method"
+	// SymbolClosure 闭包(匿名函数)
+	SymbolClosure SymbolKind = "closure"
+	// SymbolVar 全局变量
+	SymbolVar SymbolKind = "var"
+)
+
+// closureName 闭包的名称, 比如 foo.func1、foo.func1.2、foo.gowrap1
+var closureName = regexp.MustCompile(`(^|\.)(func|gowrap|deferwrap)\d+(\.\d+)*$`)
+
+// Symbol 二进制中的符号
+type Symbol struct {
+	// Name 符号全名, 比如: github.com/xxx/yyy.(*fake).call
+	Name string
+	// Package 包路径
+	Package string
+	// Receiver 方法的接收体, 比如: (*fake), 函数和变量为空
+	Receiver string
+	// Kind 符号类型
+	Kind SymbolKind
+	// Signature 函数签名, 仅在有调试信息(-ldflags="-s=false -w=false")时可用, 变量为 nil
+	Signature reflect.Type
+}
+
+// String 符号描述
+func (s Symbol) String() string {
+	if s.Signature != nil {
+		return string(s.Kind) + " " + s.Name + " " + s.Signature.String()
+	}
+	return string(s.Kind) + " " + s.Name
+}
+
+// Symbols 查找名称匹配 pattern 的函数、方法、闭包和全局变量, 按照名称排序
+// pattern 支持通配符(*匹配任意字符, ?匹配单个字符), 比如: github.com/xxx/yyy.(*fake).*;
+// 也支持正则表达式, 比如: ^github.com/xxx/yyy\..*call$
+func Symbols(pattern string) []Symbol {
+	match := symbolMatcher(pattern)
+	funcs, err := unexports2.FunctionSymbols()
+	if err != nil {
+		panic(err)
+	}
+	vars, err := unexports2.VariableSymbols()
+	if err != nil {
+		panic(err)
+	}
+
+	result := make([]Symbol, 0, 16)
+	for _, sym := range funcs {
+		if match(sym.Name) {
+			s := newSymbol(sym)
+			s.Signature, _ = unexports2.FuncSignature(sym.Name)
+			result = append(result, s)
+		}
+	}
+	for _, sym := range vars {
+		if match(sym.Name) {
+			s := newSymbol(sym)
+			s.Kind = SymbolVar
+			result = append(result, s)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// newSymbol 根据符号表中的符号解析包路径、接收体和类型
+func newSymbol(sym gosym.Sym) Symbol {
+	s := Symbol{
+		Name:     sym.Name,
+		Package:  sym.PackageName(),
+		Receiver: sym.ReceiverName(),
+		Kind:     SymbolFunc,
+	}
+	switch {
+	case closureName.MatchString(strings.TrimPrefix(sym.Name, s.Package+".")):
+		s.Kind = SymbolClosure
+	case s.Receiver != "":
+		s.Kind = SymbolMethod
+	}
+	return s
+}
+
+// symbolMatcher 构造符号名称的匹配函数, 通配符或者正则表达式任意一种匹配即可
+func symbolMatcher(pattern string) func(name string) bool {
+	glob := regexp.MustCompile("^" + strings.NewReplacer(`\*`, ".*", `\?`, ".").
+		Replace(regexp.QuoteMeta(pattern)) + "$")
+	re, err := regexp.Compile(pattern)
+	return func(name string) bool {
+		return glob.MatchString(name) || (err == nil && re.MatchString(name))
+	}
+}
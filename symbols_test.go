@@ -0,0 +1,80 @@
+// Package mocker_test 对 mocker 包的测试
+// 当前文件实现了对 symbols.go 的单测
+package mocker_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	mocker "github.com/tencent/goom"
+	"github.com/tencent/goom/test"
+)
+
+// TestUnitSymbolsTestSuite 测试入口
+func TestUnitSymbolsTestSuite(t *testing.T) {
+	suite.Run(t, new(symbolsTestSuite))
+}
+
+type symbolsTestSuite struct {
+	suite.Suite
+}
+
+// TestUnitSymbolsGlob 测试使用通配符查找符号
+func (s *symbolsTestSuite) TestUnitSymbolsGlob() {
+	s.Run("success", func() {
+		_ = test.NewUnexportedFake().Invokecall(1)
+		symbols := mocker.Symbols("github.com/tencent/goom/test.(*fake).c*")
+		s.NotEmpty(symbols, "symbols check")
+
+		var call *mocker.Symbol
+		for i := range symbols {
+			if symbols[i].Name == "github.com/tencent/goom/test.(*fake).call" {
+				call = &symbols[i]
+			}
+		}
+		s.NotNil(call, "method symbol check")
+		s.Equal(mocker.SymbolMethod, call.Kind, "method kind check")
+		s.Equal("github.com/tencent/goom/test", call.Package, "method package check")
+		s.Equal("(*fake)", call.Receiver, "method receiver check")
+	})
+}
+
+// TestUnitSymbolsRegexp 测试使用正则表达式查找函数、闭包和变量
+func (s *symbolsTestSuite) TestUnitSymbolsRegexp() {
+	s.Run("success", func() {
+		_ = test.UnexportedGlobalIntVar()
+		kinds := make(map[string]mocker.SymbolKind)
+		for _, sym := range mocker.Symbols(`^github\.com/tencent/goom/test\.(foo|foo\.func1|unexportedGlobalIntVar)$`) {
+			kinds[sym.Name] = sym.Kind
+		}
+		s.Equal(mocker.SymbolFunc, kinds["github.com/tencent/goom/test.foo"], "func kind check")
+		s.Equal(mocker.SymbolClosure, kinds["github.com/tencent/goom/test.foo.func1"], "closure kind check")
+		// 变量符号来自符号表, 没有符号表时跳过
+		if _, ok := kinds["github.com/tencent/goom/test.unexportedGlobalIntVar"]; !ok {
+			s.T().Skip("var symbols are not available, build with -ldflags=\"-s=false\"")
+		}
+		s.Equal(mocker.SymbolVar, kinds["github.com/tencent/goom/test.unexportedGlobalIntVar"], "var kind check")
+	})
+}
+
+// TestUnitFuncNotFoundSuggestion 测试未导出函数名错误时给出相似的函数名提示
+func (s *symbolsTestSuite) TestUnitFuncNotFoundSuggestion() {
+	s.Run("success", func() {
+		var expectErr string
+		func() {
+			defer func() {
+				expectErr = fmt.Sprint(recover())
+			}()
+
+			mock := mocker.Create()
+			defer mock.Reset()
+			mock.Pkg("github.com/tencent/goom/test").ExportFunc("fooo").Apply(func(i int) int {
+				return i
+			})
+		}()
+
+		s.Contains(expectErr, "func not found: github.com/tencent/goom/test.fooo", "func not found check")
+		s.Contains(expectErr, "* github.com/tencent/goom/test.foo", "func not found suggestion check")
+	})
+}
@@ -32,6 +32,18 @@ func Invokefoo(i int) int {
 	return foo(i)
 }
 
+// Adder 返回捕获了 base 变量的闭包, 用于测试闭包 mock
+//
+//go:noinline
+func Adder(base int) func(i int) int {
+	return func(i int) int {
+		if i < -10000 {
+			dummy()
+		}
+		return base + i
+	}
+}
+
 // fake 未导出结构体
 type fake struct {
 	field1 string
@@ -39,3 +39,59 @@ func (f *Fake) Call2(i int) int {
 	}
 	return i
 }
+
+// Store 用于测试接口的所有实现类 mock
+type Store interface {
+	Get(key string) string
+}
+
+// MemStore Store 的指针接收体实现
+type MemStore struct {
+	prefix string
+}
+
+// Get 获取值
+func (s *MemStore) Get(key string) string {
+	return "mem:" + s.prefix + key
+}
+
+// FileStore Store 的值接收体实现
+type FileStore struct {
+	dir string
+}
+
+// Get 获取值
+func (s FileStore) Get(key string) string {
+	return "file:" + s.dir + key
+}
+
+// Inner 用于测试嵌入结构体的提升方法 mock
+type Inner struct {
+	v int
+}
+
+// Name 指针接收体方法
+//
+//go:noinline
+func (i *Inner) Name() string {
+	return "inner"
+}
+
+// Value 值接收体方法
+//
+//go:noinline
+func (i Inner) Value() int {
+	return i.v
+}
+
+// Outer 嵌入了 Inner 的结构体
+type Outer struct {
+	Inner
+	x int
+}
+
+// OffsetOuter 在非零偏移量处嵌入了 Inner 的结构体
+type OffsetOuter struct {
+	X int
+	Inner
+}
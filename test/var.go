@@ -3,6 +3,7 @@ package test
 // unexportedGlobalIntVar 用于测试全局变量 mock
 var (
 	unexportedGlobalIntVar           = 1
+	unexportedGlobalInt64Var         = int64(1)
 	unexportedGlobalStrVar           = "str"
 	unexportedGlobalMapVar           = map[string]int{"key": 1}
 	unexportedGlobalArrVar           = []int{1, 2, 3}
@@ -15,6 +16,11 @@ func UnexportedGlobalIntVar() int {
 	return unexportedGlobalIntVar
 }
 
+// UnexportedGlobalInt64Var 获取未导出Int64全局变量
+func UnexportedGlobalInt64Var() int64 {
+	return unexportedGlobalInt64Var
+}
+
 // UnexportedGlobalStrVar 获取未导出Str全局变量
 func UnexportedGlobalStrVar() string {
 	return unexportedGlobalStrVar
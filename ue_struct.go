@@ -0,0 +1,166 @@
+// Package mocker 定义了 mock 的外层用户使用 API 定义,
+// 包括函数、方法、接口、未导出函数(或方法的)的 Mocker 的实现。
+// 当前文件实现了对未导出结构体内存布局的支持:
+// 从调试信息中读取未导出结构体的真实类型, 提供接收体字段的访问、fake 结构体源码的生成,
+// 以及 fake 结构体和真实类型内存布局的一致性校验。
+package mocker
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+	"unsafe"
+
+	"github.com/tencent/goom/erro"
+	"github.com/tencent/goom/internal/unexports2"
+)
+
+// StructCtx 未导出结构体实例的上下文, 用于在回调函数中访问接收体的字段
+type StructCtx struct {
+	typ reflect.Type
+	ptr unsafe.Pointer
+}
+
+// Type 未导出结构体的真实类型
+func (c *StructCtx) Type() reflect.Type {
+	return c.typ
+}
+
+// Field 获取字段, 返回值可以直接读取和修改(包括未导出字段)
+func (c *StructCtx) Field(name string) reflect.Value {
+	field, ok := c.typ.FieldByName(name)
+	if !ok {
+		panic(erro.NewFieldNotFoundError(c.typ.String(), name))
+	}
+	return reflect.NewAt(field.Type, unsafe.Pointer(uintptr(c.ptr)+field.Offset)).Elem()
+}
+
+// Type 未导出结构体的真实类型, 从调试信息(或 typelinks)中查找
+func (m *CachedUnexportedMethodMocker) Type() reflect.Type {
+	typ, err := unexports2.FindTypeByName(m.typeName())
+	if err != nil {
+		panic(err)
+	}
+	return typ
+}
+
+// Ctx 创建接收体的上下文, 用于访问接收体的字段
+// receiver 回调函数的接收体, 可以是任意指针类型(比如 fake 结构体指针)或 unsafe.Pointer
+func (m *CachedUnexportedMethodMocker) Ctx(receiver interface{}) *StructCtx {
+	v := reflect.ValueOf(receiver)
+	if v.Kind() != reflect.Ptr && v.Kind() != reflect.UnsafePointer {
+		panic(erro.NewIllegalParamTypeError("receiver", fmt.Sprintf("%T", receiver), "ptr"))
+	}
+	return &StructCtx{
+		typ: m.Type(),
+		ptr: unsafe.Pointer(v.Pointer()),
+	}
+}
+
+// FakeSource 生成和未导出结构体内存布局一致的 fake 结构体源码
+// 无法在其它包中引用的字段类型会用相同大小和对齐的数组占位
+func (m *CachedUnexportedMethodMocker) FakeSource(fakeName string) string {
+	typ := m.Type()
+	if typ.Kind() != reflect.Struct {
+		panic(erro.NewIllegalParamTypeError(m.typeName(), typ.Kind().String(), "struct"))
+	}
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "// %s 和 %s 的内存布局一致\ntype %s struct {\n", fakeName, m.typeName(), fakeName)
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if expr, ok := typeExpr(f.Type); ok {
+			_, _ = fmt.Fprintf(&b, "\t%s %s\n", f.Name, expr)
+		} else {
+			_, _ = fmt.Fprintf(&b, "\t%s %s // %s\n", f.Name, placeholder(f.Type), f.Type.String())
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// typeName 未导出结构体的全名
+func (m *UnexportedMethodMocker) typeName() string {
+	return m.pkgName + "." + strings.Trim(m.structName, "()*")
+}
+
+// checkLayout 校验回调函数接收体的 fake 结构体和真实类型的内存布局是否一致
+// 找不到真实类型(比如没有调试信息)时不做校验
+func checkLayout(typName string, callback interface{}) {
+	funcTyp := reflect.TypeOf(callback)
+	if funcTyp == nil || funcTyp.Kind() != reflect.Func || funcTyp.NumIn() == 0 {
+		return
+	}
+	fake := funcTyp.In(0)
+	if fake.Kind() == reflect.Ptr {
+		fake = fake.Elem()
+	}
+	if fake.Kind() != reflect.Struct {
+		return
+	}
+	typ, err := unexports2.FindTypeByName(typName)
+	if err != nil || typ == fake || typ.Kind() != reflect.Struct {
+		return
+	}
+	if reason := layoutDiff(fake, typ); reason != "" {
+		panic(erro.NewLayoutNotMatchError(typName, fake.String(), reason))
+	}
+}
+
+// layoutDiff 比较两个结构体的内存布局, 返回不匹配的原因, 匹配时返回空字符串
+func layoutDiff(fake, typ reflect.Type) string {
+	if fake.Size() != typ.Size() {
+		return fmt.Sprintf("size: %d, expect: %d", fake.Size(), typ.Size())
+	}
+	if fake.NumField() != typ.NumField() {
+		return fmt.Sprintf("fields length: %d, expect: %d", fake.NumField(), typ.NumField())
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		f, expect := fake.Field(i), typ.Field(i)
+		if f.Offset != expect.Offset || f.Type.Size() != expect.Type.Size() {
+			return fmt.Sprintf("field[%d] %s offset: %d, size: %d, expect: %s offset: %d, size: %d", i,
+				f.Name, f.Offset, f.Type.Size(), expect.Name, expect.Offset, expect.Type.Size())
+		}
+	}
+	return ""
+}
+
+// typeExpr 获取类型在其它包中的引用表达式, 无法引用时返回 false
+func typeExpr(typ reflect.Type) (string, bool) {
+	if typ.Name() != "" {
+		if typ.PkgPath() == "" {
+			return typ.String(), true
+		}
+		exported := unicode.IsUpper([]rune(typ.Name())[0])
+		return typ.String(), exported && !strings.Contains("/"+typ.PkgPath()+"/", "/internal/")
+	}
+	switch typ.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Chan, reflect.Map:
+		if typ.Kind() == reflect.Map {
+			if _, ok := typeExpr(typ.Key()); !ok {
+				return "", false
+			}
+		}
+		if _, ok := typeExpr(typ.Elem()); !ok {
+			return "", false
+		}
+		return typ.String(), true
+	case reflect.Func, reflect.Interface, reflect.Struct:
+		if typ.Kind() == reflect.Interface && typ.NumMethod() == 0 {
+			return typ.String(), true
+		}
+		// 匿名的函数、接口、结构体不展开, 使用占位
+		return "", false
+	default:
+		return typ.String(), true
+	}
+}
+
+// placeholder 生成和类型大小、对齐一致的数组类型
+func placeholder(typ reflect.Type) string {
+	elem, ok := map[int]string{2: "uint16", 4: "uint32", 8: "uint64"}[typ.Align()]
+	if !ok {
+		return fmt.Sprintf("[%d]byte", typ.Size())
+	}
+	return fmt.Sprintf("[%d]%s", typ.Size()/uintptr(typ.Align()), elem)
+}
@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"unsafe"
 
+	"github.com/tencent/goom/erro"
 	"github.com/tencent/goom/internal/logger"
 	"github.com/tencent/goom/internal/unexports2"
 )
@@ -13,18 +14,21 @@ import (
 type UnExportedVarMock interface {
 	Mocker
 	VarMock
-	// Set 设置变量值, val 类型必须和变量指针指向的值的类型一致
+	// Set 设置变量值, val 类型必须和变量的类型一致
 	Set(val interface{})
+	// Type 变量的类型, 从调试信息中读取, 没有调试信息时返回 nil
+	Type() reflect.Type
 }
 
 // unExportedVarMocker 未导出变量 mock 实现
 type unExportedVarMocker struct {
 	*defaultVarMocker
-	target      unsafe.Pointer
-	typ         reflect.Type
-	mockValue   interface{}
-	originValue interface{}
-	canceled    bool // canceled 是否被取消
+	path   string
+	target unsafe.Pointer
+	// typ 变量的类型, 从调试信息中读取, 没有调试信息时为 nil
+	typ reflect.Type
+	// size 变量的大小, 从符号表中读取, 符号表中没有记录时为 0
+	size uintptr
 }
 
 // NewUnExportedVarMocker 创建 UnExportedVarMock
@@ -34,29 +38,122 @@ func NewUnExportedVarMocker(path string) UnExportedVarMock {
 	if err != nil {
 		panic(fmt.Sprintf("cannot find unexported var: %s, cause by %v", path, err))
 	}
+	typ, _ := unexports2.VarType(path)
+	varMocker := newVarMocker(reflect.Value{})
+	varMocker.name = path
 	return &unExportedVarMocker{
-		defaultVarMocker: newVarMocker(reflect.Value{}),
+		defaultVarMocker: varMocker,
+		path:             path,
 		target:           unsafe.Pointer(addr),
-		canceled:         false,
+		typ:              typ,
+		size:             unexports2.VarSize(path),
 	}
 }
 
 // String mock 的名称或描述, 方便调试和问题排查
 func (m *unExportedVarMocker) String() string {
-	return fmt.Sprintf("var at[%d]", m.target)
+	if m.targetValue.IsValid() {
+		return m.defaultVarMocker.String()
+	}
+	typ := "unknown"
+	if m.typ != nil {
+		typ = m.typ.String()
+	}
+	return fmt.Sprintf("var %s(%s)", m.path, typ)
+}
+
+// Type 变量的类型, 从调试信息中读取, 没有调试信息时返回 nil
+func (m *unExportedVarMocker) Type() reflect.Type {
+	return m.typ
 }
 
 // Set 设置变量值
-// value 变量值, 必须和变量原值的类型一致，否则会出现不可预测的异常行为
-//  1. 可以是指针类型，
-//  2. 也可以是非指针类型 比如struct A{}
-//  3. 基本类型 reflect.Int, reflect.Slice, reflect.Map 等
+// value 变量值, 必须和变量原值的类型一致:
+//  1. 有调试信息时, value 的类型必须和变量的类型相同, 或者是可以转换的大小相同的同种类型(比如 64 位平台上 int64 变量可以设置 int 值)
+//  2. 没有调试信息时, value 类型的大小必须和符号表中记录的变量大小一致
 //
 // 注意: Set 会覆盖之前设定 Apply 的值
 func (m *unExportedVarMocker) Set(value interface{}) {
-	m.typ = reflect.TypeOf(value)
-	m.targetValue = reflect.NewAt(m.typ, m.target)
-	// TODO 检查类型是否匹配
-	m.defaultVarMocker.doSet(value)
+	m.doSet(m.convert(reflect.ValueOf(value)))
+	logger.Consolefc(logger.DebugLevel, "mocker [%s] apply.", logger.Caller(5), m.String())
+}
+
+// Apply 变量取值回调函数, 只会执行一次
+// callback 回调函数, 可以是 func() T, 也可以是 func(old T) T: 根据变量的原值计算新值
+// 注意: Apply 会覆盖之前设定 Set 的值
+func (m *unExportedVarMocker) Apply(callback interface{}) {
+	f := reflect.ValueOf(callback)
+	if f.Kind() != reflect.Func || f.Type().NumIn() > 1 || f.Type().NumOut() != 1 {
+		panic(erro.NewIllegalParamTypeError("callback", fmt.Sprintf("%T", callback), "func() T or func(old T) T"))
+	}
+	var args []reflect.Value
+	if f.Type().NumIn() == 1 {
+		typ := m.checkType(f.Type().In(0))
+		old := reflect.NewAt(typ, m.target).Elem()
+		args = append(args, m.convertTo(old, f.Type().In(0)))
+	}
+	m.doSet(m.convert(f.Call(args)[0]))
 	logger.Consolefc(logger.DebugLevel, "mocker [%s] apply.", logger.Caller(5), m.String())
 }
+
+// doSet 将校验后的值写入变量
+func (m *unExportedVarMocker) doSet(value reflect.Value) {
+	m.targetValue = reflect.NewAt(value.Type(), m.target)
+	m.defaultVarMocker.doSet(value.Interface())
+}
+
+// convert 校验值的类型, 并转换为变量的类型
+func (m *unExportedVarMocker) convert(value reflect.Value) reflect.Value {
+	if !value.IsValid() {
+		if m.typ == nil {
+			panic(erro.NewVarTypeNotMatchError(m.path, "nil", "unknown", "type of nil value can not be inferred"))
+		}
+		return reflect.Zero(m.typ)
+	}
+	return m.convertTo(value, m.checkType(value.Type()))
+}
+
+// convertTo 将值转换为指定类型
+func (m *unExportedVarMocker) convertTo(value reflect.Value, typ reflect.Type) reflect.Value {
+	if value.Type() == typ {
+		return value
+	}
+	return value.Convert(typ)
+}
+
+// sameKind 判断两个类型是否为同种类型, 有符号整数(int、int64 等)之间和无符号整数之间视为同种类型, 大小由调用方校验
+func sameKind(a, b reflect.Type) bool {
+	if a.Kind() == b.Kind() {
+		return true
+	}
+	return (isSignedInt(a.Kind()) && isSignedInt(b.Kind())) || (isUnsignedInt(a.Kind()) && isUnsignedInt(b.Kind()))
+}
+
+// isSignedInt 是否为有符号整数类型
+func isSignedInt(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+// isUnsignedInt 是否为无符号整数类型
+func isUnsignedInt(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
+
+// checkType 校验值类型和变量类型是否匹配, 返回写入变量时使用的类型
+func (m *unExportedVarMocker) checkType(typ reflect.Type) reflect.Type {
+	if m.typ != nil {
+		if typ == m.typ || (sameKind(typ, m.typ) && typ.ConvertibleTo(m.typ) && typ.Size() == m.typ.Size()) {
+			return m.typ
+		}
+		if typ.AssignableTo(m.typ) {
+			return m.typ
+		}
+		panic(erro.NewVarTypeNotMatchError(m.path, typ.String(), m.typ.String(),
+			fmt.Sprintf("size: %d, expect: %d", typ.Size(), m.typ.Size())))
+	}
+	if m.size != 0 && typ.Size() != m.size {
+		panic(erro.NewVarTypeNotMatchError(m.path, typ.String(), "unknown",
+			fmt.Sprintf("size: %d, expect: %d", typ.Size(), m.size)))
+	}
+	return typ
+}
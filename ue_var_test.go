@@ -4,10 +4,13 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"reflect"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/suite"
 	"github.com/tencent/goom"
+	"github.com/tencent/goom/erro"
 	"github.com/tencent/goom/test"
 )
 
@@ -107,3 +110,62 @@ func (s *ueVarMockerTestSuite) TestNewUeConstMock() {
 		fmt.Println("unexportedGlobalIntConst: ", test.UnexportedGlobalIntConst())
 	})
 }
+
+// TestUeVarTypeNotMatch 测试设置和变量类型不匹配的值
+func (s *ueVarMockerTestSuite) TestUeVarTypeNotMatch() {
+	s.Run("success", func() {
+		m := mocker.Create().UnExportedVar("github.com/tencent/goom/test.unexportedGlobalIntVar")
+		defer func() {
+			err, _ := recover().(error)
+			s.IsType(&erro.VarTypeNotMatch{}, err, "var type not match check")
+			s.Equal(1, test.UnexportedGlobalIntVar(), "unexported global int var result check")
+		}()
+		m.Set(int32(3))
+	})
+}
+
+// TestUeVarSameSizeInt 测试 int64 变量设置大小相同的 int 值
+func (s *ueVarMockerTestSuite) TestUeVarSameSizeInt() {
+	s.Run("success", func() {
+		if strconv.IntSize != 64 {
+			s.T().Skip("int is not 64 bits")
+		}
+		mock := mocker.Create()
+		m := mock.UnExportedVar("github.com/tencent/goom/test.unexportedGlobalInt64Var")
+		m.Set(3)
+		s.Equal(int64(3), test.UnexportedGlobalInt64Var(), "unexported global int64 var result check")
+		m.Apply(func(old int) int {
+			return old + 1
+		})
+		s.Equal(int64(4), test.UnexportedGlobalInt64Var(), "unexported global int64 var apply check")
+		mock.Reset()
+		s.Equal(int64(1), test.UnexportedGlobalInt64Var(), "unexported global int64 var reset check")
+	})
+}
+
+// TestUeVarApplyWithOld 测试根据变量原值计算新值
+func (s *ueVarMockerTestSuite) TestUeVarApplyWithOld() {
+	s.Run("success", func() {
+		m := mocker.Create().UnExportedVar("github.com/tencent/goom/test.unexportedGlobalStrVar")
+		m.Apply(func(old string) string {
+			return old + "-mocked"
+		})
+		s.Equal("str-mocked", test.UnexportedGlobalStrVar(), "unexported global str var result check")
+		m.Cancel()
+		s.Equal("str", test.UnexportedGlobalStrVar(), "unexported global str var result check")
+	})
+}
+
+// TestUeVarType 测试从调试信息中读取变量类型
+func (s *ueVarMockerTestSuite) TestUeVarType() {
+	s.Run("success", func() {
+		m := mocker.Create().UnExportedVar("github.com/tencent/goom/test.unexportedGlobalMapVar")
+		if m.Type() == nil {
+			s.T().Skip("dwarf is not available, build with -ldflags=\"-w=false\"")
+		}
+		s.Equal(reflect.TypeOf(map[string]int{}), m.Type(), "var type check")
+		m.Set(map[string]int{"key": 2})
+		s.Equal(map[string]int{"key": 2}, test.UnexportedGlobalMapVar(), "unexported global map var result check")
+		m.Cancel()
+	})
+}
@@ -3,8 +3,11 @@ package mocker
 import (
 	"fmt"
 	"reflect"
+	"unsafe"
 
+	"github.com/tencent/goom/arg"
 	"github.com/tencent/goom/internal/logger"
+	"github.com/tencent/goom/internal/unexports2"
 )
 
 // VarMock 变量 mock
@@ -18,15 +21,30 @@ type VarMock interface {
 
 // defaultVarMocker 默认变量 mock 实现
 type defaultVarMocker struct {
+	// name 变量名, 为空时根据变量地址从符号表中反查
+	name string
+	// naming 延迟计算变量名, 第一次调用 String 时才反查符号表
+	naming      func() string
 	targetValue reflect.Value
 	mockValue   interface{}
-	originValue interface{}
+	// originValue 变量原值的拷贝, 只在第一次设置时保存, 以便多次 Set 之后依然能恢复到原值
+	originValue reflect.Value
 	canceled    bool // canceled 是否被取消
 }
 
 // String mock 的名称或描述, 方便调试和问题排查
+// 变量名从符号表中反查, mock 生效时包含原值和 mock 值, 比如: var github.com/xxx/yyy.limit(int): 100 => 10
 func (m *defaultVarMocker) String() string {
-	return fmt.Sprintf("var at[%d]", m.targetValue.Pointer())
+	if m.name == "" && m.naming != nil {
+		m.name = m.naming()
+	} else if m.name == "" {
+		m.name = varName(m.targetValue.Pointer())
+	}
+	s := fmt.Sprintf("var %s(%s)", m.name, m.targetValue.Type().Elem())
+	if m.originValue.IsValid() {
+		s += fmt.Sprintf(": %s => %s", sprintValue(m.originValue), sprintValue(m.targetValue.Elem()))
+	}
+	return s
 }
 
 // NewVarMocker 创建 VarMock
@@ -58,12 +76,19 @@ func (m *defaultVarMocker) Apply(callback interface{}) {
 	}
 
 	m.doSet(ret[0].Interface())
-	logger.Consolefc(logger.DebugLevel, "mocker [%s] apply.", logger.Caller(5), m.String())
+	if logger.IsDebugOpen() {
+		logger.Consolefc(logger.DebugLevel, "mocker [%s] apply.", logger.Caller(5), m.String())
+	}
 }
 
-// Cancel 取消 mock
+// Cancel 取消 mock, 恢复变量原值
 func (m *defaultVarMocker) Cancel() {
-	m.targetValue.Elem().Set(reflect.ValueOf(m.originValue))
+	if m.originValue.IsValid() {
+		// 按原值的类型写回, 变量指针的类型在多次 Set 之间可能不同
+		target := reflect.NewAt(m.originValue.Type(), unsafe.Pointer(m.targetValue.Pointer()))
+		target.Elem().Set(m.originValue)
+		m.originValue = reflect.Value{}
+	}
 	m.canceled = true
 }
 
@@ -76,12 +101,47 @@ func (m *defaultVarMocker) Canceled() bool {
 // 注意: Set 会覆盖之前设定 Apply 的值
 func (m *defaultVarMocker) Set(value interface{}) {
 	m.doSet(value)
-	logger.Consolefc(logger.DebugLevel, "mocker [%s] apply.", logger.Caller(5), m.String())
+	if logger.IsDebugOpen() {
+		logger.Consolefc(logger.DebugLevel, "mocker [%s] apply.", logger.Caller(5), m.String())
+	}
 }
 
 func (m *defaultVarMocker) doSet(value interface{}) {
-	m.originValue = m.targetValue.Elem().Interface()
+	if !m.originValue.IsValid() {
+		m.originValue = reflect.New(m.targetValue.Elem().Type()).Elem()
+		m.originValue.Set(m.targetValue.Elem())
+	}
 	d := reflect.ValueOf(value)
+	if !d.IsValid() {
+		d = reflect.Zero(m.targetValue.Elem().Type())
+	}
 	m.targetValue.Elem().Set(d)
 	m.mockValue = value
+	m.canceled = false
+}
+
+// maxValueLen 日志中打印的变量值的最大长度
+const maxValueLen = 64
+
+// varName 根据变量地址从符号表中反查变量名, 查找不到时(比如局部变量、堆上的对象)返回地址
+func varName(addr uintptr) string {
+	if name, offset, ok := unexports2.FindVarByAddr(addr); ok {
+		if offset == 0 {
+			return name
+		}
+		return fmt.Sprintf("%s+%d", name, offset)
+	}
+	return fmt.Sprintf("at[0x%x]", addr)
+}
+
+// sprintValue 格式化变量值, 过长的值会被截断
+func sprintValue(v reflect.Value) string {
+	if !v.IsValid() {
+		return "<none>"
+	}
+	s := arg.SprintV([]reflect.Value{v})
+	if r := []rune(s); len(r) > maxValueLen {
+		return string(r[:maxValueLen]) + "..."
+	}
+	return s
 }
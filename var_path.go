@@ -0,0 +1,212 @@
+// Package mocker 定义了 mock 的外层用户使用 API 定义,
+// 包括函数、方法、接口、未导出函数(或方法的)的 Mocker 的实现。
+// 当前文件实现了变量局部的 mock: 结构体的嵌套字段、map 的单个键值、切片的单个元素,
+// 取消 mock 时精确地恢复原值(mock 前不存在的 map 键会被删除)。
+package mocker
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unsafe"
+
+	"github.com/tencent/goom/erro"
+	"github.com/tencent/goom/internal/logger"
+)
+
+// NewVarPathMocker 创建结构体嵌套字段的 VarMock
+// target 结构体变量的指针
+// path 字段路径, 以.分隔, 比如: Limits.MaxConn; 路径中的指针字段会自动解引用, 支持未导出字段
+func NewVarPathMocker(target interface{}, path string) VarMock {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		panic(erro.NewIllegalParamTypeError("target", fmt.Sprintf("%T", target), "non-nil ptr"))
+	}
+	field := v.Elem()
+	for _, name := range strings.Split(path, ".") {
+		for field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				panic(erro.NewIllegalParamError("path", path+": nil pointer before field "+name))
+			}
+			field = field.Elem()
+		}
+		if field.Kind() != reflect.Struct {
+			panic(erro.NewIllegalParamTypeError(path, field.Type().String(), "struct"))
+		}
+		f := field.FieldByName(name)
+		if !f.IsValid() {
+			panic(erro.NewFieldNotFoundError(field.Type().String(), name))
+		}
+		field = f
+	}
+	// 通过地址重新构造, 允许修改未导出字段
+	mocker := newVarMocker(reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())))
+	mocker.naming = func() string {
+		return varName(v.Pointer()) + "." + path
+	}
+	return mocker
+}
+
+// MapEntryMocker 对 map 的单个键值进行 mock
+type MapEntryMocker struct {
+	// name map 变量名, 第一次调用 String 时计算
+	name string
+	// ptr map 变量的地址, 传入的是 map 变量的指针时用于从符号表中反查变量名
+	ptr uintptr
+	m   reflect.Value
+	key reflect.Value
+	// origin mock 前的值, existed 为 false 时无效
+	origin reflect.Value
+	// existed mock 前键是否存在
+	existed  bool
+	saved    bool
+	canceled bool
+}
+
+// NewMapEntryMocker 创建 map 键值的 Mocker
+// target map 变量或者其指针
+// key 键, 类型需要和 map 的键类型一致
+func NewMapEntryMocker(target interface{}, key interface{}) *MapEntryMocker {
+	m := reflect.ValueOf(target)
+	var ptr uintptr
+	if m.Kind() == reflect.Ptr {
+		ptr = m.Pointer()
+		m = m.Elem()
+	}
+	if m.Kind() != reflect.Map || m.IsNil() {
+		panic(erro.NewIllegalParamTypeError("target", fmt.Sprintf("%T", target), "non-nil map"))
+	}
+	k := reflect.ValueOf(key)
+	if !k.IsValid() || !k.Type().AssignableTo(m.Type().Key()) {
+		panic(erro.NewIllegalParamTypeError("key", fmt.Sprintf("%T", key), m.Type().Key().String()))
+	}
+	return &MapEntryMocker{
+		ptr: ptr,
+		m:   m,
+		key: k,
+	}
+}
+
+// String mock 的名称或描述, 方便调试和问题排查
+// mock 生效时包含原值和 mock 值, 键不存在时显示为 <none>, 比如: map github.com/xxx/yyy.registry["redis"](string): <none> => fake
+func (m *MapEntryMocker) String() string {
+	if m.name == "" && m.ptr != 0 {
+		m.name = varName(m.ptr)
+	} else if m.name == "" {
+		m.name = fmt.Sprintf("at[0x%x]", m.m.Pointer())
+	}
+	s := fmt.Sprintf("map %s[%#v](%s)", m.name, m.key.Interface(), m.m.Type().Elem())
+	if m.saved {
+		s += fmt.Sprintf(": %s => %s", sprintValue(m.origin), sprintValue(m.m.MapIndex(m.key)))
+	}
+	return s
+}
+
+// Set 设置键对应的值
+// 注意: Set 会覆盖之前设定 Apply 的值
+func (m *MapEntryMocker) Set(value interface{}) {
+	m.doSet(value)
+	if logger.IsDebugOpen() {
+		logger.Consolefc(logger.DebugLevel, "mocker [%s] apply.", logger.Caller(5), m.String())
+	}
+}
+
+// Apply 值的回调函数, 只会执行一次
+// 注意: Apply 会覆盖之前设定 Set 的值
+func (m *MapEntryMocker) Apply(callback interface{}) {
+	f := reflect.ValueOf(callback)
+	if f.Kind() != reflect.Func || f.Type().NumIn() != 0 || f.Type().NumOut() != 1 {
+		panic(erro.NewIllegalParamTypeError("callback", fmt.Sprintf("%T", callback), "func() T"))
+	}
+	m.doSet(f.Call(nil)[0].Interface())
+	if logger.IsDebugOpen() {
+		logger.Consolefc(logger.DebugLevel, "mocker [%s] apply.", logger.Caller(5), m.String())
+	}
+}
+
+// Delete 删除键, 取消 mock 时恢复
+func (m *MapEntryMocker) Delete() {
+	m.save()
+	m.m.SetMapIndex(m.key, reflect.Value{})
+	m.canceled = false
+	if logger.IsDebugOpen() {
+		logger.Consolefc(logger.DebugLevel, "mocker [%s] apply.", logger.Caller(5), m.String())
+	}
+}
+
+// Cancel 取消 mock, 恢复原值; mock 前不存在的键会被删除
+func (m *MapEntryMocker) Cancel() {
+	if m.saved {
+		if m.existed {
+			m.m.SetMapIndex(m.key, m.origin)
+		} else {
+			m.m.SetMapIndex(m.key, reflect.Value{})
+		}
+		m.saved = false
+	}
+	m.canceled = true
+}
+
+// Canceled 是否取消了 mock
+func (m *MapEntryMocker) Canceled() bool {
+	return m.canceled
+}
+
+// save 第一次修改前保存原值
+func (m *MapEntryMocker) save() {
+	if m.saved {
+		return
+	}
+	m.origin = m.m.MapIndex(m.key)
+	m.existed = m.origin.IsValid()
+	m.saved = true
+}
+
+func (m *MapEntryMocker) doSet(value interface{}) {
+	d := reflect.ValueOf(value)
+	if !d.IsValid() {
+		d = reflect.Zero(m.m.Type().Elem())
+	}
+	if !d.Type().AssignableTo(m.m.Type().Elem()) {
+		panic(erro.NewIllegalParamTypeError("value", d.Type().String(), m.m.Type().Elem().String()))
+	}
+	m.save()
+	m.m.SetMapIndex(m.key, d)
+	m.canceled = false
+}
+
+// SliceMocker 对切片的单个元素进行 mock
+type SliceMocker struct {
+	builder *Builder
+	target  reflect.Value
+}
+
+// newSliceMocker 创建切片的 Mocker, 元素的 Mocker 缓存在 builder 中, 以便统一 Reset
+func newSliceMocker(builder *Builder, target interface{}) *SliceMocker {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		panic(erro.NewIllegalParamTypeError("target", fmt.Sprintf("%T", target), "*[]T"))
+	}
+	return &SliceMocker{builder: builder, target: v}
+}
+
+// Index 指定元素的下标, 返回该元素的 VarMock
+// 注意: 元素 mock 作用于当前的底层数组, 切片扩容后对新的底层数组不生效
+func (s *SliceMocker) Index(i int) VarMock {
+	slice := s.target.Elem()
+	if i < 0 || i >= slice.Len() {
+		panic(erro.NewIllegalParamError("index", fmt.Sprintf("%d, len: %d", i, slice.Len())))
+	}
+	elem := slice.Index(i)
+	cacheKey := fmt.Sprintf("slice_%d_%d", elem.UnsafeAddr(), i)
+	if mocker, ok := s.builder.mockers[cacheKey]; ok && !mocker.Canceled() {
+		return mocker.(VarMock)
+	}
+
+	mocker := newVarMocker(reflect.NewAt(elem.Type(), unsafe.Pointer(elem.UnsafeAddr())))
+	mocker.naming = func() string {
+		return fmt.Sprintf("%s[%d]", varName(s.target.Pointer()), i)
+	}
+	s.builder.cache(cacheKey, mocker)
+	return mocker
+}
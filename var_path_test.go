@@ -0,0 +1,110 @@
+package mocker_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/tencent/goom"
+)
+
+// TestUnitVarPathTestSuite 测试入口
+func TestUnitVarPathTestSuite(t *testing.T) {
+	suite.Run(t, new(varPathMockerTestSuite))
+}
+
+type varPathMockerTestSuite struct {
+	suite.Suite
+}
+
+type limits struct {
+	MaxConn int
+	timeout int
+}
+
+type config struct {
+	Name   string
+	Limits *limits
+	err    error
+}
+
+var (
+	globalConfig   = config{Name: "cfg", Limits: &limits{MaxConn: 100, timeout: 3}}
+	globalRegistry = map[string]string{"mysql": "real-mysql"}
+	globalHosts    = []string{"a", "b"}
+)
+
+// TestUnitVarPath 测试结构体嵌套字段的 mock
+func (s *varPathMockerTestSuite) TestUnitVarPath() {
+	s.Run("success", func() {
+		errFake := errors.New("fake error")
+		mock := mocker.Create()
+		mock.VarPath(&globalConfig, "Limits.MaxConn").Set(10)
+		mock.VarPath(&globalConfig, "Limits.timeout").Set(1)
+		mock.VarPath(&globalConfig, "err").Set(errFake)
+		s.Equal(10, globalConfig.Limits.MaxConn, "field mock check")
+		s.Equal(1, globalConfig.Limits.timeout, "unexported field mock check")
+		s.Equal(errFake, globalConfig.err, "interface field mock check")
+		s.Equal("cfg", globalConfig.Name, "other field check")
+
+		mock.Reset()
+		s.Equal(100, globalConfig.Limits.MaxConn, "field restore check")
+		s.Equal(3, globalConfig.Limits.timeout, "unexported field restore check")
+		s.Nil(globalConfig.err, "nil interface field restore check")
+	})
+}
+
+// TestUnitMapEntry 测试 map 单个键值的 mock
+func (s *varPathMockerTestSuite) TestUnitMapEntry() {
+	s.Run("success", func() {
+		mock := mocker.Create()
+		mock.MapEntry(globalRegistry, "mysql").Set("fake-mysql")
+		mock.MapEntry(globalRegistry, "redis").Set("fake-redis")
+		s.Equal(map[string]string{"mysql": "fake-mysql", "redis": "fake-redis"}, globalRegistry, "map entry mock check")
+
+		mock.Reset()
+		s.Equal(map[string]string{"mysql": "real-mysql"}, globalRegistry, "map entry restore check")
+	})
+	s.Run("delete", func() {
+		mock := mocker.Create()
+		mock.MapEntry(&globalRegistry, "mysql").Delete()
+		s.Empty(globalRegistry, "map entry delete check")
+
+		mock.Reset()
+		s.Equal(map[string]string{"mysql": "real-mysql"}, globalRegistry, "map entry restore check")
+	})
+}
+
+// TestUnitSliceIndex 测试切片单个元素的 mock
+func (s *varPathMockerTestSuite) TestUnitSliceIndex() {
+	s.Run("success", func() {
+		mock := mocker.Create()
+		mock.Slice(&globalHosts).Index(0).Set("x")
+		mock.Slice(&globalHosts).Index(0).Set("y")
+		s.Equal([]string{"y", "b"}, globalHosts, "slice index mock check")
+
+		mock.Reset()
+		s.Equal([]string{"a", "b"}, globalHosts, "slice index restore check")
+	})
+}
+
+// TestUnitVarString 测试变量 mock 的描述包含变量名、类型、原值和 mock 值
+func (s *varPathMockerTestSuite) TestUnitVarString() {
+	s.Run("success", func() {
+		mock := mocker.Create()
+		defer mock.Reset()
+		m := mock.VarPath(&globalConfig, "Limits.MaxConn")
+		m.Set(10)
+		s.Equal("var github.com/tencent/goom_test.globalConfig.Limits.MaxConn(int): 100 => 10", m.String(),
+			"var path name check")
+
+		v := mock.Var(&globalHosts)
+		v.Set([]string{"x"})
+		s.Equal("var github.com/tencent/goom_test.globalHosts([]string): [a b] => [x]", v.String(), "var name check")
+
+		e := mock.MapEntry(&globalRegistry, "redis")
+		e.Set("fake-redis")
+		s.Equal(`map github.com/tencent/goom_test.globalRegistry["redis"](string): <none> => fake-redis`, e.String(),
+			"map entry name check")
+	})
+}
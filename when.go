@@ -6,6 +6,7 @@ package mocker
 
 import (
 	"reflect"
+	"sync"
 
 	"github.com/tencent/goom/arg"
 	"github.com/tencent/goom/erro"
@@ -32,6 +33,8 @@ type When struct {
 	defaultReturns Matcher
 	// curMatch 当前指定的参数匹配
 	curMatch Matcher
+	// locker 不为 nil 时修改条件需要持有该锁, 由可能被并发调用的 Mocker(比如间谍)指定, 匹配时由 Mocker 持有
+	locker sync.Locker
 }
 
 // CreateWhen 构造条件判断
@@ -119,6 +122,8 @@ func NewWhen(funTyp reflect.Type) *When {
 //
 //	When(3, 4, N).Return(5), // 第一个参数是3，且第二个参数是4时, 第N个参数是N时，返回5
 func (w *When) When(specArgOrExpr ...interface{}) *When {
+	w.lock()
+	defer w.unlock()
 	w.curMatch = newDefaultMatch(specArgOrExpr, nil, w.isMethod, w.funcTyp)
 	return w
 }
@@ -138,16 +143,34 @@ func (w *When) When(specArgOrExpr ...interface{}) *When {
 //
 //	等价于 w.When(arg.In(3, 5), arg.In(4, 6), arg.In(N))
 func (w *When) In(specArgsOrExprs ...interface{}) *When {
+	w.lock()
+	defer w.unlock()
 	w.curMatch = newContainsMatch(specArgsOrExprs, nil, w.isMethod, w.funcTyp)
 	return w
 }
 
 // Return 指定返回值
 func (w *When) Return(value ...interface{}) *When {
+	w.lock()
+	defer w.unlock()
+	w.addReturn(value)
+	return w
+}
+
+// AndReturn 指定第二次调用返回值,之后的调用以最后一个指定的值返回
+func (w *When) AndReturn(value ...interface{}) *When {
+	w.lock()
+	defer w.unlock()
+	w.addAndReturn(value)
+	return w
+}
+
+// addReturn 添加返回值, 调用时需要持有 locker
+func (w *When) addReturn(value []interface{}) {
 	if w.curMatch != nil {
 		w.curMatch.AddResult(value)
 		w.matches = append(w.matches, w.curMatch)
-		return w
+		return
 	}
 
 	if w.defaultReturns == nil {
@@ -155,16 +178,29 @@ func (w *When) Return(value ...interface{}) *When {
 	} else {
 		w.defaultReturns.AddResult(value)
 	}
-	return w
 }
 
-// AndReturn 指定第二次调用返回值,之后的调用以最后一个指定的值返回
-func (w *When) AndReturn(value ...interface{}) *When {
+// addAndReturn 添加后续调用的返回值, 调用时需要持有 locker
+func (w *When) addAndReturn(value []interface{}) {
 	if w.curMatch == nil {
-		return w.Return(value...)
+		w.addReturn(value)
+		return
 	}
 	w.curMatch.AddResult(value)
-	return w
+}
+
+// lock 存在 locker 时加锁
+func (w *When) lock() {
+	if w.locker != nil {
+		w.locker.Lock()
+	}
+}
+
+// unlock 存在 locker 时解锁
+func (w *When) unlock() {
+	if w.locker != nil {
+		w.locker.Unlock()
+	}
 }
 
 // Matches 多个条件匹配
@@ -172,6 +208,8 @@ func (w *When) Matches(argAndRet ...arg.Pair) *When {
 	if len(argAndRet) == 0 {
 		return w
 	}
+	w.lock()
+	defer w.unlock()
 	for _, v := range argAndRet {
 		args, ok := v.Args.([]interface{})
 		if !ok {
@@ -183,7 +221,7 @@ func (w *When) Matches(argAndRet ...arg.Pair) *When {
 			results = []interface{}{v.Return}
 		}
 
-		w.Return(results...)
+		w.addReturn(results)
 		matcher := newDefaultMatch(args, results, w.isMethod, w.funcTyp)
 		w.matches = append(w.matches, matcher)
 	}
@@ -195,16 +233,17 @@ func (w *When) Returns(values ...interface{}) *When {
 	if len(values) == 0 {
 		return w
 	}
-
+	w.lock()
+	defer w.unlock()
 	for i, v := range values {
 		ret, ok := v.([]interface{})
 		if !ok {
 			ret = []interface{}{v}
 		}
 		if i == 0 {
-			w.Return(ret...)
+			w.addReturn(ret)
 		} else {
-			w.AndReturn(ret...)
+			w.addAndReturn(ret)
 		}
 	}
 	return w
@@ -222,6 +261,19 @@ func (w *When) invoke(args1 []reflect.Value) (results []reflect.Value) {
 	return w.returnDefaults()
 }
 
+// tryInvoke 执行 When 参数匹配, 没有匹配的条件且没有默认返回值时返回 false
+func (w *When) tryInvoke(args1 []reflect.Value) ([]reflect.Value, bool) {
+	for _, c := range w.matches {
+		if c.Match(args1) {
+			return c.Result(), true
+		}
+	}
+	if w.defaultReturns == nil {
+		return nil, false
+	}
+	return w.defaultReturns.Result(), true
+}
+
 // Eval 执行 when 子句
 func (w *When) Eval(args ...interface{}) []interface{} {
 	argsTypes, isVariadic := inTypes(w.isMethod, w.funcTyp)